@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	EventAIBatchProgress = "ai-batch:progress"
+	EventAIBatchDone     = "ai-batch:done"
+)
+
+// AIJob runs AI requests for a set of questions in the background: explanations
+// or topic classification. Items are persisted so a job interrupted by closing
+// the app resumes on startup.
+type AIJob struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Kind        string    `json:"kind"`   // explain (also when empty), classify
+	Filter      string    `json:"filter"` // all, mistakes, marked
+	Topics      string    `json:"topics"` // JSON list of topics for classify jobs
+	Status      string    `json:"status"` // running, paused, done, cancelled
+	RPM         int       `json:"rpm"`
+	Concurrency int       `json:"concurrency"`
+	Total       int       `json:"total"`
+	Done        int       `json:"done"`
+	Failed      int       `json:"failed"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type AIJobItem struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	JobID      uint      `gorm:"index" json:"job_id"`
+	QuestionID uint      `json:"question_id"`
+	Status     string    `json:"status"` // pending, done, failed
+	Error      string    `json:"error"`
+	Tries      int       `json:"tries"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type AIBatchOptions struct {
+	Filter      string `json:"filter"`
+	RPM         int    `json:"rpm"`
+	Concurrency int    `json:"concurrency"`
+}
+
+// batchRunner tracks the single job that may run at a time.
+type batchRunner struct {
+	mu     sync.Mutex
+	jobID  uint
+	cancel context.CancelFunc
+}
+
+// questionIDsByFilter returns the IDs of questions in the given subset.
+func (a *App) questionIDsByFilter(filter string) ([]uint, error) {
+	var ids []uint
+	query := a.db.Model(&Question{}).Where("bank_id = ? AND retired = ?", a.activeBank, false)
+	switch filter {
+	case "", "all":
+	case "mistakes":
+		query = query.Where("id IN (?)", a.mine().Model(&MistakeBook{}).Select("question_id"))
+	case "marked":
+		query = query.Where("id IN (?)", a.mine().Model(&UserProgress{}).Select("question_id").Where("is_marked = ?", true))
+	default:
+		return nil, errors.New("未知的题目范围: " + filter)
+	}
+	query.Order("id ASC").Pluck("id", &ids)
+	return ids, nil
+}
+
+// questionIDsBySelection extends questionIDsByFilter with the tag filter used by exports.
+func (a *App) questionIDsBySelection(filter string, tagID uint) ([]uint, error) {
+	if filter != "tag" {
+		return a.questionIDsByFilter(filter)
+	}
+	var tag Tag
+	if err := a.db.First(&tag, tagID).Error; err != nil || tag.BankID != a.activeBank {
+		return nil, errors.New("标签不存在")
+	}
+	var ids []uint
+	a.bankQuestionIDs().
+		Where("id IN (?)", a.db.Model(&QuestionTag{}).Select("question_id").Where("tag_id = ?", tag.ID)).
+		Order("id ASC").Pluck("id", &ids)
+	return ids, nil
+}
+
+// StartAIBatch queues every question in the filter that has no AI explanation yet.
+func (a *App) StartAIBatch(opts AIBatchOptions) (AIJob, error) {
+	if opts.RPM <= 0 {
+		opts.RPM = 20
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 2
+	}
+
+	if a.batchRunning() {
+		return AIJob{}, errors.New("已有批量任务在运行")
+	}
+
+	ids, err := a.questionIDsByFilter(opts.Filter)
+	if err != nil {
+		return AIJob{}, err
+	}
+	var missing []uint
+	if len(ids) > 0 {
+		a.db.Model(&Question{}).Where("id IN ? AND (ai_explanation = '' OR ai_explanation IS NULL)", ids).Order("id ASC").Pluck("id", &missing)
+	}
+	if len(missing) == 0 {
+		return AIJob{}, errors.New("所选范围内的题目都已有 AI 解析")
+	}
+
+	return a.queueAIJob(AIJob{
+		Kind:        "explain",
+		Filter:      opts.Filter,
+		RPM:         opts.RPM,
+		Concurrency: opts.Concurrency,
+	}, missing)
+}
+
+// queueAIJob stores a job with one pending item per question and starts it.
+// The running check and the start share the lock so two jobs can't race in.
+func (a *App) queueAIJob(job AIJob, ids []uint) (AIJob, error) {
+	a.batch.mu.Lock()
+	defer a.batch.mu.Unlock()
+	if a.batch.jobID != 0 {
+		return AIJob{}, errors.New("已有批量任务在运行")
+	}
+
+	job.Status = "running"
+	job.Total = len(ids)
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&job).Error; err != nil {
+			return err
+		}
+		items := make([]AIJobItem, 0, len(ids))
+		for _, id := range ids {
+			items = append(items, AIJobItem{JobID: job.ID, QuestionID: id, Status: "pending"})
+		}
+		return tx.CreateInBatches(items, 200).Error
+	})
+	if err != nil {
+		return AIJob{}, err
+	}
+
+	a.runAIJob(job)
+	return job, nil
+}
+
+func (a *App) batchRunning() bool {
+	a.batch.mu.Lock()
+	defer a.batch.mu.Unlock()
+	return a.batch.jobID != 0
+}
+
+// resumeAIJobs restarts a job that was still running when the app closed.
+func (a *App) resumeAIJobs() {
+	var job AIJob
+	if err := a.db.Where("status = ?", "running").Order("id DESC").First(&job).Error; err == nil {
+		a.batch.mu.Lock()
+		a.runAIJob(job)
+		a.batch.mu.Unlock()
+	}
+}
+
+// runAIJob starts the workers for a job. The caller holds a.batch.mu.
+func (a *App) runAIJob(job AIJob) {
+	// Stored jobs may come from older versions or an edited database
+	job.RPM = max(job.RPM, 1)
+	job.Concurrency = max(job.Concurrency, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.batch.jobID = job.ID
+	a.batch.cancel = cancel
+
+	go func() {
+		defer cancel()
+
+		var items []AIJobItem
+		a.db.Where("job_id = ? AND status = ?", job.ID, "pending").Order("id ASC").Find(&items)
+
+		work := make(chan AIJobItem)
+		var wg sync.WaitGroup
+		for i := 0; i < job.Concurrency; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for item := range work {
+					a.processAIJobItem(ctx, job, item)
+				}
+			}()
+		}
+
+		// Requests are spaced evenly to stay under the per-minute limit
+		limiter := time.NewTicker(time.Minute / time.Duration(job.RPM))
+		defer limiter.Stop()
+	feed:
+		for i, item := range items {
+			if i > 0 {
+				select {
+				case <-ctx.Done():
+					break feed
+				case <-limiter.C:
+				}
+			}
+			select {
+			case <-ctx.Done():
+				break feed
+			case work <- item:
+			}
+		}
+		close(work)
+		wg.Wait()
+
+		a.batch.mu.Lock()
+		// Paused or cancelled jobs already have their final status
+		if ctx.Err() == nil {
+			a.db.Model(&AIJob{}).Where("id = ?", job.ID).Update("status", "done")
+		}
+		a.batch.jobID = 0
+		a.batch.cancel = nil
+		a.batch.mu.Unlock()
+		a.emit(EventAIBatchDone, a.GetAIBatch(job.ID))
+	}()
+}
+
+func (a *App) processAIJobItem(ctx context.Context, job AIJob, item AIJobItem) {
+	var q Question
+	err := a.db.First(&q, item.QuestionID).Error
+	if err == nil {
+		switch job.Kind {
+		case "classify":
+			err = a.classifyQuestion(ctx, job, &q)
+		default:
+			if q.AIExplanation == "" {
+				_, err = a.generateExplanation(ctx, &q)
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		// Leave the item pending so it is picked up when the job resumes
+		return
+	}
+
+	item.Tries++
+	column := "done"
+	if err != nil {
+		item.Status = "failed"
+		item.Error = err.Error()
+		column = "failed"
+	} else {
+		item.Status = "done"
+		item.Error = ""
+	}
+	a.db.Save(&item)
+	a.db.Model(&AIJob{}).Where("id = ?", item.JobID).UpdateColumn(column, gorm.Expr(column+" + 1"))
+
+	a.emit(EventAIBatchProgress, a.GetAIBatch(item.JobID))
+}
+
+func (a *App) stopAIJob(id uint, status string) error {
+	a.batch.mu.Lock()
+	defer a.batch.mu.Unlock()
+	if a.batch.jobID != id || a.batch.cancel == nil {
+		return errors.New("任务未在运行")
+	}
+	a.db.Model(&AIJob{}).Where("id = ?", id).Update("status", status)
+	a.batch.cancel()
+	return nil
+}
+
+// PauseAIBatch stops the job but keeps its pending items for ResumeAIBatch.
+func (a *App) PauseAIBatch(id uint) error {
+	return a.stopAIJob(id, "paused")
+}
+
+func (a *App) CancelAIBatch(id uint) error {
+	return a.stopAIJob(id, "cancelled")
+}
+
+func (a *App) ResumeAIBatch(id uint) error {
+	return a.restartAIJob(id, nil)
+}
+
+// RetryAIBatchFailures puts failed items back in the queue and resumes the job.
+func (a *App) RetryAIBatchFailures(id uint) error {
+	return a.restartAIJob(id, func(job AIJob) error {
+		return a.db.Transaction(func(tx *gorm.DB) error {
+			res := tx.Model(&AIJobItem{}).Where("job_id = ? AND status = ?", id, "failed").Update("status", "pending")
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				return errors.New("没有失败的题目")
+			}
+			return tx.Model(&job).UpdateColumn("failed", gorm.Expr("failed - ?", res.RowsAffected)).Error
+		})
+	})
+}
+
+// restartAIJob runs prepare and starts the job again, refusing cancelled jobs.
+// Everything happens under the lock so nothing changes if the job can't start.
+func (a *App) restartAIJob(id uint, prepare func(job AIJob) error) error {
+	a.batch.mu.Lock()
+	defer a.batch.mu.Unlock()
+
+	var job AIJob
+	if err := a.db.First(&job, id).Error; err != nil {
+		return errors.New("任务不存在")
+	}
+	if job.Status == "cancelled" {
+		return errors.New("任务已取消")
+	}
+	if a.batch.jobID != 0 {
+		return errors.New("已有批量任务在运行")
+	}
+	if prepare != nil {
+		if err := prepare(job); err != nil {
+			return err
+		}
+	}
+
+	job.Status = "running"
+	a.db.Model(&job).Update("status", job.Status)
+	a.runAIJob(job)
+	return nil
+}
+
+func (a *App) GetAIBatch(id uint) AIJob {
+	var job AIJob
+	a.db.First(&job, id)
+	return job
+}
+
+// ListAIBatches returns all jobs, newest first.
+func (a *App) ListAIBatches() []AIJob {
+	jobs := []AIJob{}
+	a.db.Order("id DESC").Find(&jobs)
+	return jobs
+}
+
+func (a *App) GetAIBatchFailures(id uint) []AIJobItem {
+	items := []AIJobItem{}
+	a.db.Where("job_id = ? AND status = ?", id, "failed").Order("id ASC").Find(&items)
+	return items
+}
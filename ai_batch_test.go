@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// A stored job with a zero rate or concurrency must still run to the end.
+func TestResumeAIBatchClampsStoredLimits(t *testing.T) {
+	a := newTestApp(t)
+	job := AIJob{Kind: "explain", Status: "paused", Total: 1}
+	a.db.Create(&job)
+	// A missing question fails without calling the AI
+	a.db.Create(&AIJobItem{JobID: job.ID, QuestionID: 999999, Status: "pending"})
+
+	if err := a.ResumeAIBatch(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for a.batchRunning() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	got := a.GetAIBatch(job.ID)
+	if got.Status != "done" || got.Failed != 1 {
+		t.Errorf("status %q, %d failed; want done, 1", got.Status, got.Failed)
+	}
+}
+
+func TestResumeAIBatchRefusesCancelled(t *testing.T) {
+	a := newTestApp(t)
+	job := AIJob{Kind: "explain", Status: "cancelled", RPM: 10, Concurrency: 1}
+	a.db.Create(&job)
+	if err := a.ResumeAIBatch(job.ID); err == nil {
+		t.Error("cancelled job resumed")
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	EventChatStart = "ai-chat:start"
+	EventChatChunk = "ai-chat:chunk"
+	EventChatDone  = "ai-chat:done"
+	EventChatError = "ai-chat:error"
+)
+
+// Only the most recent messages are sent back to the model
+const chatHistoryLimit = 20
+
+// ChatMessage is one turn of a follow-up conversation about a question.
+// The question context is rebuilt for every request rather than stored, so
+// the thread follows answer-key and explanation updates.
+type ChatMessage struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ProfileID  uint      `gorm:"index" json:"profile_id"`
+	QuestionID uint      `gorm:"index" json:"question_id"`
+	Role       string    `json:"role"` // user, assistant
+	Content    string    `json:"content"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func chatSeed(q Question) string {
+	var opts []string
+	json.Unmarshal([]byte(q.Options), &opts)
+
+	var sb strings.Builder
+	sb.WriteString("你是一个专业的政治课助教，正在和学生讨论下面这道题。请结合题目、标准答案和已有解析回答学生的追问，回答要简洁准确。\n\n")
+	fmt.Fprintf(&sb, "题型：%s\n题目：%s\n选项：\n%s\n标准答案：%s\n", q.Type, q.Content, strings.Join(opts, "\n"), q.Answer)
+	if q.Explanation != "" {
+		fmt.Fprintf(&sb, "参考解析：%s\n", q.Explanation)
+	}
+	if q.AIExplanation != "" {
+		fmt.Fprintf(&sb, "AI 解析：%s\n", q.AIExplanation)
+	}
+	return sb.String()
+}
+
+// chatRequest builds the request with the thread history and the new message.
+// The message is stored by the caller along with the reply.
+func (a *App) chatRequest(ai aiBackend, questionID uint, message string) (openai.ChatCompletionRequest, error) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return openai.ChatCompletionRequest{}, errors.New("消息不能为空")
+	}
+	var q Question
+	if err := a.db.First(&q, questionID).Error; err != nil {
+		return openai.ChatCompletionRequest{}, errors.New("题目不存在")
+	}
+	if _, ok := a.inActiveExam(questionID); ok {
+		return openai.ChatCompletionRequest{}, errors.New("考试中不能向 AI 提问")
+	}
+
+	var history []ChatMessage
+	a.mine().Where("question_id = ?", questionID).Order("id DESC").Limit(chatHistoryLimit).Find(&history)
+
+	messages := []openai.ChatCompletionMessage{
+		{Role: openai.ChatMessageRoleSystem, Content: chatSeed(q)},
+	}
+	for i := len(history) - 1; i >= 0; i-- {
+		messages = append(messages, openai.ChatCompletionMessage{
+			Role:    history[i].Role,
+			Content: history[i].Content,
+		})
+	}
+	messages = append(messages, openai.ChatCompletionMessage{
+		Role:    openai.ChatMessageRoleUser,
+		Content: message,
+	})
+
+	return openai.ChatCompletionRequest{
+		Model:       ai.Model,
+		Temperature: ai.Temperature,
+		Messages:    messages,
+	}, nil
+}
+
+func (a *App) saveChatMessage(profileID, questionID uint, role, content string) ChatMessage {
+	msg := ChatMessage{
+		ProfileID:  profileID,
+		QuestionID: questionID,
+		Role:       role,
+		Content:    content,
+	}
+	a.db.Create(&msg)
+	return msg
+}
+
+// AskAI sends a follow-up question and waits for the full reply.
+func (a *App) AskAI(questionID uint, message string) (ChatMessage, error) {
+	profileID := a.activeProfile
+	ai := a.ai()
+	req, err := a.chatRequest(ai, questionID, message)
+	if err != nil {
+		return ChatMessage{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ai.timeout())
+	defer cancel()
+	resp, err := ai.client.CreateChatCompletion(ctx, req)
+	if err != nil {
+		return ChatMessage{}, fmt.Errorf("AI 回复失败: %w", err)
+	}
+	if len(resp.Choices) == 0 {
+		return ChatMessage{}, errors.New("AI 回复失败: 返回结果为空")
+	}
+	a.saveChatMessage(profileID, questionID, openai.ChatMessageRoleUser, strings.TrimSpace(message))
+	return a.saveChatMessage(profileID, questionID, openai.ChatMessageRoleAssistant, resp.Choices[0].Message.Content), nil
+}
+
+// AskAIStream sends a follow-up question and streams the reply through ai-chat:* events.
+func (a *App) AskAIStream(questionID uint, message string) error {
+	// Reserve the slot before unlocking so a second send is refused
+	ctx, cancel := context.WithCancel(context.Background())
+	a.streamMu.Lock()
+	if _, running := a.chatStreams[questionID]; running {
+		a.streamMu.Unlock()
+		cancel()
+		return errors.New("AI 正在回复中")
+	}
+	a.chatStreams[questionID] = cancel
+	a.streamMu.Unlock()
+
+	done := func() {
+		a.streamMu.Lock()
+		delete(a.chatStreams, questionID)
+		a.streamMu.Unlock()
+		cancel()
+	}
+
+	profileID := a.activeProfile
+	ai := a.ai()
+	req, err := a.chatRequest(ai, questionID, message)
+	if err != nil {
+		done()
+		return err
+	}
+	req.Stream = true
+
+	go func() {
+		defer done()
+
+		text, err := a.runChatStream(ctx, ai, questionID, req)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				err = errors.New("已取消")
+			}
+			a.emit(EventChatError, AIStreamEvent{QuestionID: questionID, Error: err.Error()})
+			return
+		}
+		// Both turns are saved together so a failed reply leaves no unanswered question
+		a.saveChatMessage(profileID, questionID, openai.ChatMessageRoleUser, strings.TrimSpace(message))
+		reply := a.saveChatMessage(profileID, questionID, openai.ChatMessageRoleAssistant, text)
+		a.emit(EventChatDone, AIStreamEvent{QuestionID: questionID, Text: reply.Content})
+	}()
+	return nil
+}
+
+func (a *App) runChatStream(ctx context.Context, ai aiBackend, questionID uint, req openai.ChatCompletionRequest) (string, error) {
+	stream, err := ai.client.CreateChatCompletionStream(ctx, req)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	a.emit(EventChatStart, AIStreamEvent{QuestionID: questionID})
+
+	var sb strings.Builder
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if len(resp.Choices) == 0 || resp.Choices[0].Delta.Content == "" {
+			continue
+		}
+		delta := resp.Choices[0].Delta.Content
+		sb.WriteString(delta)
+		a.emit(EventChatChunk, AIStreamEvent{QuestionID: questionID, Delta: delta})
+	}
+
+	if sb.Len() == 0 {
+		return "", errors.New("返回结果为空")
+	}
+	return sb.String(), nil
+}
+
+func (a *App) CancelAskAI(questionID uint) {
+	a.streamMu.Lock()
+	defer a.streamMu.Unlock()
+	if cancel, ok := a.chatStreams[questionID]; ok {
+		cancel()
+	}
+}
+
+// GetChatThread returns the conversation about a question, oldest first.
+func (a *App) GetChatThread(questionID uint) []ChatMessage {
+	messages := []ChatMessage{}
+	a.mine().Where("question_id = ?", questionID).Order("id ASC").Find(&messages)
+	return messages
+}
+
+func (a *App) ClearChatThread(questionID uint) {
+	a.mine().Where("question_id = ?", questionID).Delete(&ChatMessage{})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func waitChatStream(t *testing.T, a *App, questionID uint) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		a.streamMu.Lock()
+		_, running := a.chatStreams[questionID]
+		a.streamMu.Unlock()
+		if !running {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("chat stream did not finish")
+}
+
+func TestAskAIStreamSavesOnlyAnsweredTurns(t *testing.T) {
+	var fail atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"好的\"}}]}\n\n")
+		if fail.Load() {
+			// Cut the stream off before it is done
+			panic(http.ErrAbortHandler)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	a := newTestApp(t)
+	a.applyAISettings(AISettings{BaseURL: srv.URL, APIKey: "test", Model: "m", Timeout: 5})
+	const id = 1
+
+	fail.Store(true)
+	if err := a.AskAIStream(id, "为什么选 B？"); err != nil {
+		t.Fatal(err)
+	}
+	waitChatStream(t, a, id)
+	if thread := a.GetChatThread(id); len(thread) != 0 {
+		t.Fatalf("failed reply left %d messages", len(thread))
+	}
+
+	fail.Store(false)
+	if err := a.AskAIStream(id, "为什么选 B？"); err != nil {
+		t.Fatal(err)
+	}
+	waitChatStream(t, a, id)
+	thread := a.GetChatThread(id)
+	if len(thread) != 2 || thread[0].Role != "user" || thread[1].Content != "好的" {
+		t.Fatalf("thread = %+v; want the question and the reply", thread)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+	"gorm.io/gorm"
+)
+
+// TopicSuggestion is a topic the AI picked for a question. It only becomes a
+// tag once the user accepts it.
+type TopicSuggestion struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	JobID      uint      `gorm:"index" json:"job_id"`
+	QuestionID uint      `gorm:"index" json:"question_id"`
+	Topic      string    `json:"topic"`
+	Confidence float64   `json:"confidence"` // 0-1 as reported by the model
+	Reason     string    `json:"reason"`
+	Status     string    `gorm:"index" json:"status"` // pending, accepted, rejected
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type AIClassifyOptions struct {
+	Topics      []string `json:"topics"` // Empty to use the tags of the active bank
+	Filter      string   `json:"filter"`
+	RPM         int      `json:"rpm"`
+	Concurrency int      `json:"concurrency"`
+}
+
+// StartAIClassification asks the AI to put every question in the filter into
+// one of the topics. Questions with a suggestion waiting for review are skipped.
+func (a *App) StartAIClassification(opts AIClassifyOptions) (AIJob, error) {
+	if opts.RPM <= 0 {
+		opts.RPM = 20
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = 2
+	}
+	if a.batchRunning() {
+		return AIJob{}, errors.New("已有批量任务在运行")
+	}
+
+	var topics []string
+	seen := make(map[string]bool)
+	for _, t := range opts.Topics {
+		t = strings.TrimSpace(t)
+		if t != "" && !seen[t] {
+			seen[t] = true
+			topics = append(topics, t)
+		}
+	}
+	if len(topics) == 0 {
+		for _, tag := range a.ListTags() {
+			topics = append(topics, tag.Name)
+		}
+	}
+	if len(topics) < 2 {
+		return AIJob{}, errors.New("请至少提供两个章节")
+	}
+
+	ids, err := a.questionIDsByFilter(opts.Filter)
+	if err != nil {
+		return AIJob{}, err
+	}
+	var todo []uint
+	if len(ids) > 0 {
+		a.db.Model(&Question{}).
+			Where("id IN ? AND id NOT IN (?)", ids, a.db.Model(&TopicSuggestion{}).Select("question_id").Where("status = ?", "pending")).
+			Order("id ASC").Pluck("id", &todo)
+	}
+	if len(todo) == 0 {
+		return AIJob{}, errors.New("所选范围内没有需要分类的题目")
+	}
+
+	data, _ := json.Marshal(topics)
+	return a.queueAIJob(AIJob{
+		Kind:        "classify",
+		Filter:      opts.Filter,
+		Topics:      string(data),
+		RPM:         opts.RPM,
+		Concurrency: opts.Concurrency,
+	}, todo)
+}
+
+type classifyResponse struct {
+	Topic      string  `json:"topic"`
+	Confidence float64 `json:"confidence"`
+	Reason     string  `json:"reason"`
+}
+
+func (a *App) classifyQuestion(ctx context.Context, job AIJob, q *Question) error {
+	var topics []string
+	json.Unmarshal([]byte(job.Topics), &topics)
+
+	var list strings.Builder
+	for i, t := range topics {
+		fmt.Fprintf(&list, "%d. %s\n", i+1, t)
+	}
+	prompt := fmt.Sprintf(`
+请判断下面这道题属于哪一个章节。
+章节列表：
+%s
+题目：%s
+选项：%s
+
+要求：
+1. 只能从章节列表中选择一个，原样返回章节名称。
+2. 必须返回合法的 JSON 格式，包含 "topic" (章节名称)、"confidence" (0 到 1 之间的把握程度) 和 "reason" (一句话理由) 三个字段。
+`, list.String(), q.Content, q.Options)
+
+	ai := a.ai()
+	ctx, cancel := context.WithTimeout(ctx, ai.timeout())
+	defer cancel()
+	resp, err := ai.client.CreateChatCompletion(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model:       ai.Model,
+			Temperature: 0,
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: "你是一个帮助整理题库的AI助手。请以JSON格式输出。",
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: prompt,
+				},
+			},
+			ResponseFormat: &openai.ChatCompletionResponseFormat{
+				Type: openai.ChatCompletionResponseFormatTypeJSONObject,
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if len(resp.Choices) == 0 {
+		return errors.New("返回结果为空")
+	}
+
+	var result classifyResponse
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &result); err != nil {
+		return fmt.Errorf("无法解析返回结果: %w", err)
+	}
+	topic := strings.TrimSpace(result.Topic)
+	valid := false
+	for _, t := range topics {
+		if t == topic {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return fmt.Errorf("返回的章节不在列表中: %s", topic)
+	}
+	if result.Confidence < 0 {
+		result.Confidence = 0
+	} else if result.Confidence > 1 {
+		result.Confidence = 1
+	}
+
+	return a.db.Create(&TopicSuggestion{
+		JobID:      job.ID,
+		QuestionID: q.ID,
+		Topic:      topic,
+		Confidence: result.Confidence,
+		Reason:     result.Reason,
+		Status:     "pending",
+	}).Error
+}
+
+type TopicSuggestionView struct {
+	TopicSuggestion
+	Number  uint   `json:"number"`
+	Content string `json:"content"`
+}
+
+// GetTopicSuggestions lists suggestions of a job (0 for all jobs) in the given
+// status, least confident first so doubtful ones are reviewed before the rest.
+func (a *App) GetTopicSuggestions(jobID uint, status string) []TopicSuggestionView {
+	query := a.db.Table("topic_suggestions").
+		Select("topic_suggestions.*, questions.number, questions.content").
+		Joins("JOIN questions ON questions.id = topic_suggestions.question_id").
+		Where("questions.id IN (?)", a.bankQuestionIDs())
+	if jobID != 0 {
+		query = query.Where("topic_suggestions.job_id = ?", jobID)
+	}
+	if status != "" {
+		query = query.Where("topic_suggestions.status = ?", status)
+	}
+
+	views := []TopicSuggestionView{}
+	query.Order("topic_suggestions.confidence ASC, questions.number ASC").Scan(&views)
+	return views
+}
+
+// AcceptTopicSuggestions turns pending suggestions into tags.
+func (a *App) AcceptTopicSuggestions(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		var suggestions []TopicSuggestion
+		tx.Where("id IN ? AND status = ?", ids, "pending").Find(&suggestions)
+		for _, s := range suggestions {
+			var q Question
+			if err := tx.First(&q, s.QuestionID).Error; err != nil {
+				return err
+			}
+			tagID, err := ensureTag(tx, q.BankID, s.Topic)
+			if err != nil {
+				return err
+			}
+			link := QuestionTag{QuestionID: q.ID, TagID: tagID, Source: "ai"}
+			if err := tx.Where("question_id = ? AND tag_id = ?", q.ID, tagID).FirstOrCreate(&link).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&s).Update("status", "accepted").Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AcceptConfidentSuggestions accepts every pending suggestion of a job with at
+// least the given confidence and returns how many were accepted.
+func (a *App) AcceptConfidentSuggestions(jobID uint, minConfidence float64) (int, error) {
+	var ids []uint
+	a.db.Model(&TopicSuggestion{}).
+		Where("job_id = ? AND status = ? AND confidence >= ?", jobID, "pending", minConfidence).
+		Pluck("id", &ids)
+	return len(ids), a.AcceptTopicSuggestions(ids)
+}
+
+func (a *App) RejectTopicSuggestions(ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return a.db.Model(&TopicSuggestion{}).Where("id IN ? AND status = ?", ids, "pending").Update("status", "rejected").Error
+}
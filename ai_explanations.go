@@ -0,0 +1,184 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+
+	"quiz-app/internal/quizbank"
+)
+
+// Prompt versions, bumped whenever a prompt changes meaningfully
+const (
+	PromptVersionJSON   = "json-v1"
+	PromptVersionStream = "stream-v1"
+)
+
+// AIExplanationRecord is one explanation generated by a model. A question can
+// have several; Question.AIExplanation caches the preferred or latest one.
+type AIExplanationRecord struct {
+	ID               uint      `gorm:"primaryKey" json:"id"`
+	QuestionID       uint      `gorm:"index" json:"question_id"`
+	Provider         string    `json:"provider"`
+	Model            string    `json:"model"`
+	PromptVersion    string    `json:"prompt_version"`
+	Answer           string    `json:"answer"` // Normalized
+	Analysis         string    `json:"analysis"`
+	Raw              string    `json:"raw"`
+	PromptTokens     int       `json:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens"`
+	Preferred        bool      `json:"preferred"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// providerName derives a short provider label from the endpoint, e.g. api.moonshot.cn -> moonshot.
+func providerName(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Hostname() == "" {
+		return baseURL
+	}
+	host := u.Hostname()
+	if host == "localhost" || host == "127.0.0.1" {
+		return "local"
+	}
+	parts := strings.Split(host, ".")
+	if len(parts) >= 2 {
+		return parts[len(parts)-2]
+	}
+	return host
+}
+
+// newExplanationRecord fills in the provider details of the settings the request used.
+func (a *App) newExplanationRecord(ai aiBackend, q *Question, promptVersion string, answer string, analysis string, raw string, usage openai.Usage) AIExplanationRecord {
+	return AIExplanationRecord{
+		QuestionID:       q.ID,
+		Provider:         providerName(ai.BaseURL),
+		Model:            ai.Model,
+		PromptVersion:    promptVersion,
+		Answer:           quizbank.NormalizeAnswer(q.Type, answer),
+		Analysis:         strings.TrimSpace(analysis),
+		Raw:              raw,
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	}
+}
+
+// splitAnalysis separates the "答案：" and "解析：" parts of a plain-text explanation.
+func splitAnalysis(qType string, text string) (string, string) {
+	answer := parseAIAnswer(qType, text)
+	analysis := text
+	if i := strings.Index(text, "解析："); i >= 0 {
+		analysis = text[i+len("解析："):]
+	}
+	return answer, strings.TrimSpace(analysis)
+}
+
+// formatExplanation renders a record for display.
+func formatExplanation(rec AIExplanationRecord) string {
+	if rec.Analysis == "" {
+		return rec.Raw
+	}
+	label := rec.Model
+	if label == "" {
+		label = "AI"
+	}
+	return fmt.Sprintf("%s说：\n答案：%s\n解析：%s", label, rec.Answer, rec.Analysis)
+}
+
+// addExplanation stores a new record and makes it the displayed explanation
+// unless another one is explicitly preferred.
+func (a *App) addExplanation(q *Question, rec AIExplanationRecord) string {
+	a.db.Create(&rec)
+	a.refreshAIExplanation(q)
+	return formatExplanation(rec)
+}
+
+// refreshAIExplanation updates the cached explanation of a question from its records.
+func (a *App) refreshAIExplanation(q *Question) {
+	var rec AIExplanationRecord
+	err := a.db.Where("question_id = ?", q.ID).Order("preferred DESC, id DESC").First(&rec).Error
+
+	previous := q.AIAnswer
+	q.AIExplanation = ""
+	q.AIAnswer = ""
+	if err == nil {
+		q.AIExplanation = formatExplanation(rec)
+		q.AIAnswer = rec.Answer
+	}
+	// A dismissed dispute only comes back if the AI now says something else
+	if quizbank.NormalizeAnswer(q.Type, previous) != quizbank.NormalizeAnswer(q.Type, q.AIAnswer) {
+		q.DisputeDismissed = false
+	}
+	a.db.Model(q).Select("ai_explanation", "ai_answer", "dispute_dismissed").Updates(q)
+	a.reindexQuestion(*q)
+}
+
+// migrateLegacyExplanations turns explanations stored only as formatted text
+// into records, keeping the original text as the raw response.
+func (a *App) migrateLegacyExplanations() {
+	var questions []Question
+	a.db.Where("ai_explanation <> '' AND id NOT IN (?)", a.db.Model(&AIExplanationRecord{}).Select("question_id")).Find(&questions)
+	for _, q := range questions {
+		text := strings.TrimPrefix(q.AIExplanation, "kimi说：\n")
+		answer, analysis := splitAnalysis(q.Type, text)
+		a.db.Create(&AIExplanationRecord{
+			QuestionID:    q.ID,
+			Provider:      "moonshot",
+			PromptVersion: "legacy",
+			Answer:        answer,
+			Analysis:      analysis,
+			Raw:           q.AIExplanation,
+			CreatedAt:     time.Now(),
+		})
+		a.refreshAIExplanation(&q)
+	}
+}
+
+// GetAIExplanations returns every explanation of a question, newest first.
+func (a *App) GetAIExplanations(questionID uint) []AIExplanationRecord {
+	records := []AIExplanationRecord{}
+	a.db.Where("question_id = ?", questionID).Order("id DESC").Find(&records)
+	return records
+}
+
+// SetPreferredAIExplanation pins one explanation for display. Passing 0
+// unpins and falls back to the latest one.
+func (a *App) SetPreferredAIExplanation(questionID uint, recordID uint) error {
+	var q Question
+	if err := a.db.First(&q, questionID).Error; err != nil {
+		return errors.New("题目不存在")
+	}
+	if recordID != 0 {
+		var rec AIExplanationRecord
+		if err := a.db.Where("id = ? AND question_id = ?", recordID, questionID).First(&rec).Error; err != nil {
+			return errors.New("解析不存在")
+		}
+	}
+
+	a.db.Model(&AIExplanationRecord{}).Where("question_id = ?", questionID).Update("preferred", false)
+	if recordID != 0 {
+		a.db.Model(&AIExplanationRecord{}).Where("id = ?", recordID).Update("preferred", true)
+	}
+	a.refreshAIExplanation(&q)
+	return nil
+}
+
+func (a *App) DeleteAIExplanation(recordID uint) error {
+	var rec AIExplanationRecord
+	if err := a.db.First(&rec, recordID).Error; err != nil {
+		return errors.New("解析不存在")
+	}
+	a.db.Delete(&rec)
+
+	var q Question
+	if err := a.db.First(&q, rec.QuestionID).Error; err == nil {
+		a.refreshAIExplanation(&q)
+	}
+	return nil
+}
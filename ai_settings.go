@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// AISettings configures the OpenAI-compatible endpoint used for explanations.
+// They live in a config file outside the DB so the key never ends up in backups.
+type AISettings struct {
+	BaseURL     string  `json:"base_url"`
+	APIKey      string  `json:"api_key"`
+	Model       string  `json:"model"`
+	Temperature float32 `json:"temperature"`
+	Timeout     int     `json:"timeout"` // Seconds
+}
+
+type AISettingsView struct {
+	AISettings
+	KeySource string `json:"key_source"` // config, env or empty
+}
+
+func defaultAISettings() AISettings {
+	return AISettings{
+		BaseURL:     "https://api.moonshot.cn/v1",
+		Model:       "kimi-k2-turbo-preview",
+		Temperature: 0.3,
+		Timeout:     60,
+	}
+}
+
+// Environment variables checked for the API key when the config has none
+var aiKeyEnv = []string{"QUIZ_AI_API_KEY", "OPENAI_API_KEY", "MOONSHOT_API_KEY"}
+
+func aiSettingsPath() string {
+	return filepath.Join(appConfigDir(), "ai.json")
+}
+
+func loadAISettings() AISettings {
+	s := defaultAISettings()
+	if data, err := os.ReadFile(aiSettingsPath()); err == nil {
+		json.Unmarshal(data, &s)
+	}
+	if v := os.Getenv("QUIZ_AI_BASE_URL"); v != "" {
+		s.BaseURL = v
+	}
+	if v := os.Getenv("QUIZ_AI_MODEL"); v != "" {
+		s.Model = v
+	}
+	return s
+}
+
+// resolveAPIKey prefers the configured key and falls back to the environment.
+func resolveAPIKey(s AISettings) (string, string) {
+	if s.APIKey != "" {
+		return s.APIKey, "config"
+	}
+	for _, name := range aiKeyEnv {
+		if v := os.Getenv(name); v != "" {
+			return v, "env"
+		}
+	}
+	return "", ""
+}
+
+func maskKey(key string) string {
+	if key == "" {
+		return ""
+	}
+	if len(key) <= 8 {
+		return strings.Repeat("*", len(key))
+	}
+	return key[:3] + strings.Repeat("*", 6) + key[len(key)-4:]
+}
+
+func (s AISettings) timeout() time.Duration {
+	return time.Duration(s.Timeout) * time.Second
+}
+
+// newAIClient builds a client whose timeout covers connecting and waiting for
+// the response headers only, so streamed replies may take longer. Non-streaming
+// calls add a deadline to their context.
+func newAIClient(s AISettings) *openai.Client {
+	key, _ := resolveAPIKey(s)
+	config := openai.DefaultConfig(key)
+	config.BaseURL = strings.TrimRight(s.BaseURL, "/")
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: s.timeout(), KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = s.timeout()
+	transport.ResponseHeaderTimeout = s.timeout()
+	config.HTTPClient = &http.Client{Transport: transport}
+	return openai.NewClientWithConfig(config)
+}
+
+// aiBackend is the settings with the client built from them. Requests take
+// one snapshot, so SaveAISettings can swap both while streams are running.
+type aiBackend struct {
+	AISettings
+	client *openai.Client
+}
+
+func (a *App) ai() aiBackend {
+	a.aiMu.RLock()
+	defer a.aiMu.RUnlock()
+	return aiBackend{AISettings: a.aiSettings, client: a.aiClient}
+}
+
+func (a *App) applyAISettings(s AISettings) {
+	client := newAIClient(s)
+	a.aiMu.Lock()
+	a.aiSettings = s
+	a.aiClient = client
+	a.aiMu.Unlock()
+}
+
+// GetAISettings returns the current settings with the API key masked.
+func (a *App) GetAISettings() AISettingsView {
+	current := a.ai().AISettings
+	key, source := resolveAPIKey(current)
+	view := AISettingsView{AISettings: current, KeySource: source}
+	view.APIKey = maskKey(key)
+	return view
+}
+
+// SaveAISettings validates and persists the settings. Sending back the masked
+// key from GetAISettings keeps the stored one.
+func (a *App) SaveAISettings(s AISettings) error {
+	s.BaseURL = strings.TrimSpace(s.BaseURL)
+	s.Model = strings.TrimSpace(s.Model)
+	s.APIKey = strings.TrimSpace(s.APIKey)
+
+	if !strings.HasPrefix(s.BaseURL, "http://") && !strings.HasPrefix(s.BaseURL, "https://") {
+		return errors.New("接口地址必须以 http:// 或 https:// 开头")
+	}
+	if s.Model == "" {
+		return errors.New("模型名称不能为空")
+	}
+	if s.Temperature < 0 || s.Temperature > 2 {
+		return errors.New("temperature 必须在 0 到 2 之间")
+	}
+	if s.Timeout <= 0 {
+		s.Timeout = defaultAISettings().Timeout
+	}
+	current := a.ai().AISettings
+	if key, _ := resolveAPIKey(current); s.APIKey != "" && s.APIKey == maskKey(key) {
+		s.APIKey = current.APIKey
+	}
+
+	path := aiSettingsPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(s, "", "  ")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return err
+	}
+
+	a.applyAISettings(s)
+	return nil
+}
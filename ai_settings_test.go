@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// A streamed reply may take longer than the timeout as long as it has started,
+// a plain request may not.
+func TestAIClientTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"%d\"}}]}\n\n", i)
+			w.(http.Flusher).Flush()
+			time.Sleep(600 * time.Millisecond)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer srv.Close()
+
+	a := &App{}
+	a.applyAISettings(AISettings{BaseURL: srv.URL, APIKey: "test", Model: "m", Timeout: 1})
+	ai := a.ai()
+
+	text, err := a.runChatStream(context.Background(), ai, 1, openai.ChatCompletionRequest{Model: "m", Stream: true})
+	if err != nil || text != "012" {
+		t.Fatalf("stream = %q, %v; want 012", text, err)
+	}
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(1500 * time.Millisecond)
+	}))
+	defer slow.Close()
+	a.applyAISettings(AISettings{BaseURL: slow.URL, APIKey: "test", Model: "m", Timeout: 1})
+	start := time.Now()
+	if _, err := a.ai().client.CreateChatCompletion(context.Background(), openai.ChatCompletionRequest{Model: "m"}); err == nil {
+		t.Error("request without response headers did not time out")
+	}
+	if d := time.Since(start); d > 1400*time.Millisecond {
+		t.Errorf("timed out after %v", d)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Events emitted while streaming, all carrying the question ID
+const (
+	EventAIStart = "ai:start"
+	EventAIChunk = "ai:chunk"
+	EventAIDone  = "ai:done"
+	EventAIError = "ai:error"
+)
+
+type AIStreamEvent struct {
+	QuestionID uint   `json:"question_id"`
+	Delta      string `json:"delta,omitempty"`
+	Text       string `json:"text,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+func streamPrompt(q Question) string {
+	return fmt.Sprintf(`
+你是一个专业的政治课助教。请对以下题目进行解析。
+题目：%s
+选项：%s
+
+要求：
+1. 使用联网搜索功能查找相关背景知识。
+2. 第一行输出 "答案：" 加上你的答案（例如 "答案：A"），第二行开始输出 "解析：" 加上详细解析内容。
+3. 解析内容要深入浅出，逻辑清晰，可以使用 Markdown。
+`, q.Content, q.Options)
+}
+
+// StreamAIExplanation generates an explanation in the background and pushes
+// it to the frontend through ai:* events. The text is only saved once the
+// stream completes, so a cancelled or failed stream keeps the old explanation.
+func (a *App) StreamAIExplanation(id uint, force bool) error {
+	var q Question
+	if err := a.db.First(&q, id).Error; err != nil {
+		return errors.New("题目不存在")
+	}
+	if _, ok := a.inActiveExam(id); ok {
+		return errors.New("考试中不能查看 AI 解析")
+	}
+
+	if !force && q.AIExplanation != "" {
+		a.emit(EventAIDone, AIStreamEvent{QuestionID: id, Text: q.AIExplanation})
+		return nil
+	}
+
+	a.streamMu.Lock()
+	if _, running := a.streams[id]; running {
+		a.streamMu.Unlock()
+		return errors.New("该题的 AI 解析正在生成中")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	a.streams[id] = cancel
+	a.streamMu.Unlock()
+
+	go func() {
+		defer func() {
+			a.streamMu.Lock()
+			delete(a.streams, id)
+			a.streamMu.Unlock()
+			cancel()
+		}()
+
+		ai := a.ai()
+		text, usage, err := a.runExplanationStream(ctx, ai, q)
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				err = errors.New("已取消")
+			}
+			a.emit(EventAIError, AIStreamEvent{QuestionID: id, Error: err.Error()})
+			return
+		}
+
+		answer, analysis := splitAnalysis(q.Type, text)
+		rec := a.newExplanationRecord(ai, &q, PromptVersionStream, answer, analysis, text, usage)
+		a.emit(EventAIDone, AIStreamEvent{QuestionID: id, Text: a.addExplanation(&q, rec)})
+	}()
+	return nil
+}
+
+func (a *App) runExplanationStream(ctx context.Context, ai aiBackend, q Question) (string, openai.Usage, error) {
+	var usage openai.Usage
+	stream, err := ai.client.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
+		Model:       ai.Model,
+		Temperature: ai.Temperature,
+		Stream:      true,
+		Messages: []openai.ChatCompletionMessage{
+			{
+				Role:    openai.ChatMessageRoleSystem,
+				Content: "你是一个帮助学生学习的AI助手。",
+			},
+			{
+				Role:    openai.ChatMessageRoleUser,
+				Content: streamPrompt(q),
+			},
+		},
+	})
+	if err != nil {
+		return "", usage, err
+	}
+	defer stream.Close()
+
+	a.emit(EventAIStart, AIStreamEvent{QuestionID: q.ID})
+
+	var sb strings.Builder
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return "", usage, err
+		}
+		if resp.Usage != nil {
+			usage = *resp.Usage
+		}
+		if len(resp.Choices) == 0 {
+			continue
+		}
+		delta := resp.Choices[0].Delta.Content
+		if delta == "" {
+			continue
+		}
+		sb.WriteString(delta)
+		a.emit(EventAIChunk, AIStreamEvent{QuestionID: q.ID, Delta: delta})
+	}
+
+	text := strings.TrimSpace(sb.String())
+	if text == "" {
+		return "", usage, errors.New("返回结果为空")
+	}
+	return text, usage, nil
+}
+
+// CancelAIExplanation stops a running stream for the question.
+func (a *App) CancelAIExplanation(id uint) {
+	a.streamMu.Lock()
+	defer a.streamMu.Unlock()
+	if cancel, ok := a.streams[id]; ok {
+		cancel()
+	}
+}
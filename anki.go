@@ -0,0 +1,311 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"gorm.io/gorm"
+)
+
+type AnkiExportOptions struct {
+	Filter      string `json:"filter"` // all, mistakes, marked, tag
+	TagID       uint   `json:"tag_id"` // For the tag filter
+	DeckName    string `json:"deck_name"`
+	MistakeTags bool   `json:"mistake_tags"` // Tag cards with the mistake book count, e.g. 错题::3
+}
+
+// An .apkg is a zip holding an Anki collection (SQLite, schema 11) and a media map.
+const ankiSchema = `
+CREATE TABLE col (id integer primary key, crt integer not null, mod integer not null, scm integer not null, ver integer not null, dty integer not null, usn integer not null, ls integer not null, conf text not null, models text not null, decks text not null, dconf text not null, tags text not null);
+CREATE TABLE notes (id integer primary key, guid text not null, mid integer not null, mod integer not null, usn integer not null, tags text not null, flds text not null, sfld integer not null, csum integer not null, flags integer not null, data text not null);
+CREATE TABLE cards (id integer primary key, nid integer not null, did integer not null, ord integer not null, mod integer not null, usn integer not null, type integer not null, queue integer not null, due integer not null, ivl integer not null, factor integer not null, reps integer not null, lapses integer not null, left integer not null, odue integer not null, odid integer not null, flags integer not null, data text not null);
+CREATE TABLE revlog (id integer primary key, cid integer not null, usn integer not null, ease integer not null, ivl integer not null, lastIvl integer not null, factor integer not null, time integer not null, type integer not null);
+CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+CREATE INDEX ix_notes_usn on notes (usn);
+CREATE INDEX ix_cards_usn on cards (usn);
+CREATE INDEX ix_revlog_usn on revlog (usn);
+CREATE INDEX ix_cards_nid on cards (nid);
+CREATE INDEX ix_cards_sched on cards (did, queue, due);
+CREATE INDEX ix_revlog_cid on revlog (cid);
+CREATE INDEX ix_notes_csum on notes (csum);
+`
+
+var ankiFields = []string{"题号", "题型", "题目", "选项", "答案", "解析", "AI解析"}
+
+const ankiQuestionTemplate = `<div class="type">{{题型}} · {{题号}}</div>
+<div class="content">{{题目}}</div>
+<div class="options">{{选项}}</div>`
+
+const ankiAnswerTemplate = `{{FrontSide}}
+<hr id="answer">
+<div class="answer">答案：{{答案}}</div>
+{{#解析}}<div class="explanation">{{解析}}</div>{{/解析}}
+{{#AI解析}}<div class="ai">{{AI解析}}</div>{{/AI解析}}`
+
+const ankiCSS = `.card { font-family: sans-serif; font-size: 18px; text-align: left; color: black; background-color: white; }
+.type { color: #888; font-size: 14px; margin-bottom: 8px; }
+.options { margin-top: 12px; }
+.answer { font-weight: bold; color: #2e7d32; }
+.explanation, .ai { margin-top: 12px; }
+.ai { color: #555; }`
+
+// ankiID derives stable model and deck IDs so re-imports update the same deck.
+func ankiID(name string) int64 {
+	sum := sha1.Sum([]byte(name))
+	id, _ := strconv.ParseInt(hex.EncodeToString(sum[:])[:12], 16, 64)
+	return id
+}
+
+func ankiText(s string) string {
+	return strings.ReplaceAll(html.EscapeString(s), "\n", "<br>")
+}
+
+var ankiTagSpace = regexp.MustCompile(`\s+`)
+
+func ankiTag(s string) string {
+	return ankiTagSpace.ReplaceAllString(strings.TrimSpace(s), "_")
+}
+
+// ankiChecksum is the first 8 hex digits of the SHA1 of the sort field.
+func ankiChecksum(field string) int64 {
+	sum := sha1.Sum([]byte(field))
+	v, _ := strconv.ParseInt(hex.EncodeToString(sum[:])[:8], 16, 64)
+	return v
+}
+
+// ExportAnki writes the selected questions of the active bank as an Anki deck
+// and returns the number of notes.
+func (a *App) ExportAnki(path string, opts AnkiExportOptions) (int, error) {
+	ids, err := a.questionIDsBySelection(opts.Filter, opts.TagID)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, errors.New("所选范围内没有题目")
+	}
+	var bank Bank
+	a.db.First(&bank, a.activeBank)
+	if strings.TrimSpace(opts.DeckName) == "" {
+		opts.DeckName = bank.Name
+	}
+
+	var questions []Question
+	a.db.Where("id IN ?", ids).Order("number ASC").Find(&questions)
+	mistakes := make(map[uint]int)
+	if opts.MistakeTags {
+		var rows []MistakeBook
+		a.mine().Where("question_id IN ?", ids).Find(&rows)
+		for _, m := range rows {
+			mistakes[m.QuestionID] = m.Count
+		}
+	}
+	tags := make(map[uint][]string)
+	var links []struct {
+		QuestionID uint
+		Name       string
+	}
+	a.db.Table("question_tags").Select("question_tags.question_id, tags.name").
+		Joins("JOIN tags ON tags.id = question_tags.tag_id").
+		Where("question_tags.question_id IN ?", ids).Scan(&links)
+	for _, l := range links {
+		tags[l.QuestionID] = append(tags[l.QuestionID], ankiTag(l.Name))
+	}
+
+	dir, err := os.MkdirTemp("", "quiz-anki")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+	collection := filepath.Join(dir, "collection.anki2")
+	if err := writeAnkiCollection(collection, opts.DeckName, bank.Name, questions, tags, mistakes); err != nil {
+		return 0, fmt.Errorf("生成 Anki 牌组失败: %w", err)
+	}
+	if err := writeAnkiPackage(path, collection); err != nil {
+		return 0, fmt.Errorf("写入 Anki 文件失败: %w", err)
+	}
+	return len(questions), nil
+}
+
+func writeAnkiCollection(path string, deckName string, bankName string, questions []Question, tags map[uint][]string, mistakes map[uint]int) error {
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		return err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	now := time.Now()
+	modelID := ankiID("quiz-app model")
+	deckID := ankiID("quiz-app deck " + deckName)
+
+	fields := make([]map[string]interface{}, len(ankiFields))
+	for i, name := range ankiFields {
+		fields[i] = map[string]interface{}{"name": name, "ord": i, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []string{}}
+	}
+	models := map[string]interface{}{
+		strconv.FormatInt(modelID, 10): map[string]interface{}{
+			"id": modelID, "name": "刷题助手", "type": 0, "mod": now.Unix(), "usn": -1,
+			"sortf": 2, "did": deckID, "flds": fields, "css": ankiCSS,
+			"tmpls": []map[string]interface{}{{
+				"name": "卡片 1", "ord": 0, "qfmt": ankiQuestionTemplate, "afmt": ankiAnswerTemplate,
+				"did": nil, "bqfmt": "", "bafmt": "",
+			}},
+			"latexPre":  "\\documentclass[12pt]{article}\n\\begin{document}\n",
+			"latexPost": "\\end{document}",
+			"tags":      []string{}, "vers": []string{},
+			"req": []interface{}{[]interface{}{0, "any", []int{2}}},
+		},
+	}
+	deck := func(id int64, name string) map[string]interface{} {
+		return map[string]interface{}{
+			"id": id, "name": name, "desc": "", "mod": now.Unix(), "usn": -1, "collapsed": false,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+			"dyn": 0, "extendNew": 10, "extendRev": 50, "conf": 1,
+		}
+	}
+	decks := map[string]interface{}{
+		"1":                           deck(1, "Default"),
+		strconv.FormatInt(deckID, 10): deck(deckID, deckName),
+	}
+	dconf := map[string]interface{}{
+		"1": map[string]interface{}{
+			"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true, "timer": 0, "replayq": true, "dyn": false,
+			"new":   map[string]interface{}{"delays": []float64{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": 2500, "order": 1, "perDay": 20, "bury": true, "separate": true},
+			"rev":   map[string]interface{}{"perDay": 200, "ease4": 1.3, "fuzz": 0.05, "maxIvl": 36500, "ivlFct": 1, "bury": true, "minSpace": 1},
+			"lapse": map[string]interface{}{"delays": []float64{10}, "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 0},
+		},
+	}
+	conf := map[string]interface{}{
+		"nextPos": len(questions) + 1, "estTimes": true, "activeDecks": []int64{deckID}, "sortType": "noteFld",
+		"timeLim": 0, "sortBackwards": false, "addToCur": true, "curDeck": deckID, "newBury": true,
+		"newSpread": 0, "dueCounts": true, "curModel": modelID, "collapseTime": 1200,
+	}
+	js := func(v interface{}) string {
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range strings.Split(ankiSchema, ";") {
+			if strings.TrimSpace(stmt) == "" {
+				continue
+			}
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Exec("INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')",
+			now.Unix(), now.UnixMilli(), now.UnixMilli(), js(conf), js(models), js(decks), js(dconf)).Error; err != nil {
+			return err
+		}
+
+		base := now.UnixMilli()
+		for i, q := range questions {
+			var opts []string
+			json.Unmarshal([]byte(q.Options), &opts)
+			for j := range opts {
+				opts[j] = ankiText(opts[j])
+			}
+			values := []string{
+				strconv.Itoa(int(q.Number)), q.Type, ankiText(q.Content), strings.Join(opts, "<br>"),
+				ankiText(q.Answer), ankiText(q.Explanation), ankiText(q.AIExplanation),
+			}
+
+			noteTags := append([]string{ankiTag(q.Type)}, tags[q.ID]...)
+			if n := mistakes[q.ID]; n > 0 {
+				noteTags = append(noteTags, fmt.Sprintf("错题::%d", n))
+			}
+
+			// Stable GUIDs let Anki update notes on re-import instead of duplicating them
+			guid := fmt.Sprintf("quiz-%s-%d", bankName, q.Number)
+			sum := sha1.Sum([]byte(guid))
+			noteID := base + int64(i)
+			if err := tx.Exec("INSERT INTO notes VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')",
+				noteID, hex.EncodeToString(sum[:])[:10], modelID, now.Unix(),
+				" "+strings.Join(noteTags, " ")+" ", strings.Join(values, "\x1f"),
+				values[2], ankiChecksum(values[2])).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec("INSERT INTO cards VALUES (?, ?, ?, 0, ?, -1, 0, 0, ?, 0, 0, 0, 0, 0, 0, 0, 0, '')",
+				noteID, noteID, deckID, now.Unix(), i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func writeAnkiPackage(path string, collection string) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(f)
+	err = func() error {
+		w, err := zw.Create("collection.anki2")
+		if err != nil {
+			return err
+		}
+		in, err := os.Open(collection)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		if _, err := io.Copy(w, in); err != nil {
+			return err
+		}
+		w, err = zw.Create("media")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write([]byte("{}"))
+		return err
+	}()
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ExportAnkiDialog asks where to save the deck and exports it.
+// An empty path is returned if the dialog was cancelled.
+func (a *App) ExportAnkiDialog(opts AnkiExportOptions) (string, error) {
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "导出 Anki 牌组",
+		DefaultFilename: "quiz.apkg",
+		Filters:         []runtime.FileFilter{{DisplayName: "Anki 牌组 (*.apkg)", Pattern: "*.apkg"}},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	if filepath.Ext(path) == "" {
+		path += ".apkg"
+	}
+	_, err = a.ExportAnki(path, opts)
+	return path, err
+}
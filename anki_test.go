@@ -0,0 +1,137 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// openAnkiPackage unpacks the collection of an .apkg and opens it.
+func openAnkiPackage(t *testing.T, path string) *gorm.DB {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+
+	collection := filepath.Join(t.TempDir(), "collection.anki2")
+	for _, name := range []string{"collection.anki2", "media"} {
+		f, err := zr.Open(name)
+		if err != nil {
+			t.Fatalf("package has no %s", name)
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name == "media" {
+			if string(data) != "{}" {
+				t.Errorf("media = %q; want {}", data)
+			}
+			continue
+		}
+		if err := os.WriteFile(collection, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	db, err := gorm.Open(sqlite.Open(collection), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { closeDB(db) })
+	return db
+}
+
+func TestExportAnki(t *testing.T) {
+	a := newTestApp(t)
+	qs := bankQuestions(t, a, 3)
+	for _, q := range qs[:2] {
+		a.mine().Model(&UserProgress{}).Where("question_id = ?", q.ID).Update("is_marked", true)
+	}
+	a.db.Create(&MistakeBook{ProfileID: a.activeProfile, QuestionID: qs[0].ID, Count: 2})
+
+	path := filepath.Join(t.TempDir(), "deck.apkg")
+	n, err := a.ExportAnki(path, AnkiExportOptions{Filter: "marked", DeckName: "测试牌组", MistakeTags: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("exported %d notes; want 2", n)
+	}
+
+	db := openAnkiPackage(t, path)
+	var col struct {
+		Ver   int
+		Decks string
+	}
+	if err := db.Raw("SELECT ver, decks FROM col").Scan(&col).Error; err != nil {
+		t.Fatal(err)
+	}
+	var decks map[string]struct{ Name string }
+	if err := json.Unmarshal([]byte(col.Decks), &decks); err != nil {
+		t.Fatal(err)
+	}
+	deckID := ankiID("quiz-app deck 测试牌组")
+	if col.Ver != 11 || decks[strconv.FormatInt(deckID, 10)].Name != "测试牌组" {
+		t.Errorf("col = version %d, decks %s; want version 11 with 测试牌组", col.Ver, col.Decks)
+	}
+
+	var notes []struct {
+		ID   int64
+		Tags string
+		Flds string
+	}
+	db.Raw("SELECT id, tags, flds FROM notes ORDER BY id").Scan(&notes)
+	if len(notes) != 2 {
+		t.Fatalf("%d notes; want 2", len(notes))
+	}
+	for i, note := range notes {
+		fields := strings.Split(note.Flds, "\x1f")
+		if len(fields) != len(ankiFields) {
+			t.Fatalf("note %d has %d fields; want %d", i, len(fields), len(ankiFields))
+		}
+		if fields[0] != strconv.Itoa(int(qs[i].Number)) || fields[4] != qs[i].Answer {
+			t.Errorf("note %d = number %s, answer %s; want %d, %s", i, fields[0], fields[4], qs[i].Number, qs[i].Answer)
+		}
+		if hasTag := strings.Contains(note.Tags, " 错题::2 "); hasTag != (i == 0) {
+			t.Errorf("note %d tags = %q", i, note.Tags)
+		}
+	}
+
+	var cards []struct {
+		Nid int64
+		Did int64
+		Due int
+	}
+	db.Raw("SELECT nid, did, due FROM cards ORDER BY due").Scan(&cards)
+	if len(cards) != 2 {
+		t.Fatalf("%d cards; want 2", len(cards))
+	}
+	for i, card := range cards {
+		if card.Nid != notes[i].ID || card.Did != deckID || card.Due != i+1 {
+			t.Errorf("card %d = %+v; want note %d in deck %d, due %d", i, card, notes[i].ID, deckID, i+1)
+		}
+	}
+}
+
+func TestExportAnkiEmptySelection(t *testing.T) {
+	a := newTestApp(t)
+	path := filepath.Join(t.TempDir(), "deck.apkg")
+	if _, err := a.ExportAnki(path, AnkiExportOptions{Filter: "mistakes"}); err == nil {
+		t.Error("exporting an empty mistake book succeeded")
+	}
+	if _, err := os.Stat(path); err == nil {
+		t.Error("an empty export wrote a file")
+	}
+}
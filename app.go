@@ -4,13 +4,14 @@ import (
 	"context"
 	_ "embed"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 	"sort"
+	"sync"
+	"time"
 
-	"github.com/glebarez/sqlite"
 	"github.com/sashabaranov/go-openai"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
 	"gorm.io/gorm"
 )
 
@@ -20,22 +21,32 @@ var questionsJSON []byte
 // Models
 type Question struct {
 	ID            uint   `gorm:"primaryKey" json:"id"`
+	BankID        uint   `gorm:"index:idx_bank_number" json:"bank_id"`
+	Number        uint   `gorm:"index:idx_bank_number" json:"number"` // ID inside the bank file
 	Type          string `json:"type"`
 	Content       string `json:"content"`
 	Options       string `json:"options"` // JSON string
 	Answer        string `json:"-"`
 	Explanation   string `json:"-"`
 	AIExplanation string `json:"ai_explanation"`
+	AIAnswer      string `json:"-"` // Normalized answer given by the AI
+	ContentHash   string `gorm:"index" json:"-"`
+	Retired       bool   `gorm:"index" json:"-"` // Dropped from the bank file
+	// Set once a disagreement with the key has been checked
+	DisputeDismissed bool `json:"-"`
 }
 
 type UserProgress struct {
+	ProfileID  uint   `gorm:"primaryKey" json:"profile_id"`
 	QuestionID uint   `gorm:"primaryKey" json:"question_id"`
 	Status     int    `json:"status"` // 0: Unanswered, 1: Correct, 2: Wrong
 	UserAnswer string `json:"user_answer"`
 	IsMarked   bool   `json:"is_marked"`
+	KeyChanged bool   `json:"key_changed"` // Answer key changed since the last attempt
 }
 
 type MistakeBook struct {
+	ProfileID  uint `gorm:"primaryKey" json:"profile_id"`
 	QuestionID uint `gorm:"primaryKey" json:"question_id"`
 	Count      int  `json:"count"`
 }
@@ -45,148 +56,114 @@ type App struct {
 	ctx            context.Context
 	db             *gorm.DB
 	MistakeMode    bool
+	ReviewMode     bool
 	aiClient       *openai.Client
+	aiSettings     AISettings
+	aiMu           sync.RWMutex // Guards aiClient and aiSettings
 	mistakeSession map[uint]int
+	reviewSession  map[uint]int
+	reviewQueue    []uint
+	shownAt        map[uint]time.Time
+	scheduler      *Scheduler
+	grader         *Grader
+	activeExam     uint
+	activeBank     uint
+	activeProfile  uint
+	activeTag      uint // Practice only this tag when set
+	notesOnly      bool // Practice only questions with a note
+	lastSync       SyncSummary
+	streams        map[uint]context.CancelFunc
+	chatStreams    map[uint]context.CancelFunc
+	streamMu       sync.Mutex
+	batch          batchRunner
+	ftsReady       bool   // FTS5 search index is available
+	dbFlag         string // -db command line flag
+	dbStatus       DBStatus
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	config := openai.DefaultConfig("")
-	config.BaseURL = "https://api.moonshot.cn/v1"
-	client := openai.NewClientWithConfig(config)
-	return &App{
-		aiClient:       client,
+	a := &App{
 		mistakeSession: make(map[uint]int),
+		reviewSession:  make(map[uint]int),
+		shownAt:        make(map[uint]time.Time),
+		scheduler:      NewScheduler(),
+		grader:         NewGrader(),
+		streams:        make(map[uint]context.CancelFunc),
+		chatStreams:    make(map[uint]context.CancelFunc),
 	}
+	a.applyAISettings(loadAISettings())
+	return a
 }
 
 // startup is called when the app starts. The context is saved
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
-	a.initDB()
-}
-
-func (a *App) initDB() {
-	// Initialize SQLite DB
-	cwd, _ := os.Getwd()
-	dbPath := filepath.Join(cwd, "quiz.db")
-
-	db, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
-	if err != nil {
-		panic("failed to connect database")
+	if a.db != nil {
+		a.resumeAIJobs()
 	}
-	a.db = db
-
-	// Migrate
-	db.AutoMigrate(&Question{}, &UserProgress{}, &MistakeBook{})
+}
 
-	// Check if questions exist
-	var count int64
-	db.Model(&Question{}).Count(&count)
-	if count == 0 {
-		a.loadQuestions()
-	} else {
-		// Sync questions (e.g. fix types)
-		a.syncQuestions()
-	}
+// domReady is called once the frontend is loaded and can receive events.
+func (a *App) domReady(ctx context.Context) {
+	a.warnDBStatus()
 }
 
-func (a *App) syncQuestions() {
-	if len(questionsJSON) == 0 {
+// emit sends an event to the frontend. It is a no-op before startup.
+func (a *App) emit(name string, data ...interface{}) {
+	if a.ctx == nil {
 		return
 	}
+	runtime.EventsEmit(a.ctx, name, data...)
+}
 
-	var rawQuestions []struct {
-		ID            uint     `json:"id"`
-		Type          string   `json:"type"`
-		Content       string   `json:"content"`
-		Options       []string `json:"options"`
-		Answer        string   `json:"answer"`
-		Explanation   string   `json:"explanation"`
-		AIExplanation string   `json:"ai_explanation"`
-	}
-
-	json.Unmarshal(questionsJSON, &rawQuestions)
-
-	// Batch update might be complex with GORM for different values, so loop is fine for <10k items
-	// Or we can just check and update if needed
-	for _, rq := range rawQuestions {
-		var q Question
-		if err := a.db.First(&q, rq.ID).Error; err == nil {
-			updated := false
-			if q.Type != rq.Type {
-				q.Type = rq.Type
-				updated = true
-			}
-
-			// Check options
-			opts, _ := json.Marshal(rq.Options)
-			if q.Options != string(opts) {
-				q.Options = string(opts)
-				updated = true
-			}
-
-			// Check answer (for TF fix)
-			if q.Answer != rq.Answer {
-				q.Answer = rq.Answer
-				updated = true
-			}
-
-			if updated {
-				a.db.Save(&q)
-			}
+// initDB opens and prepares the database before the app is bound. It only
+// fails if not even the in-memory fallback opens.
+func (a *App) initDB() error {
+	moved, err := a.openDB()
+	if err != nil {
+		// Keep the app usable, domReady warns that nothing is saved
+		a.dbStatus.Error = err.Error()
+		if moved, err = a.openMemoryDB(); err != nil {
+			a.dbStatus.Error += "; " + err.Error()
+			return errors.New(a.dbStatus.Error)
 		}
 	}
-}
 
-func (a *App) loadQuestions() {
-	// Use embedded file
-	if len(questionsJSON) == 0 {
-		fmt.Println("questions.json is empty")
-		return
-	}
+	a.loadSettings()
+	a.migrateProfiles(moved)
+	a.migrateBanks()
+	// Before anything that changes questions, so their index entries are kept up to date
+	a.initSearch()
 
-	var rawQuestions []struct {
-		ID            uint     `json:"id"`
-		Type          string   `json:"type"`
-		Content       string   `json:"content"`
-		Options       []string `json:"options"`
-		Answer        string   `json:"answer"`
-		Explanation   string   `json:"explanation"`
-		AIExplanation string   `json:"ai_explanation"`
-	}
+	// Insert, update or retire questions to match the embedded bank
+	a.syncEmbeddedBank()
 
-	json.Unmarshal(questionsJSON, &rawQuestions)
-
-	for _, rq := range rawQuestions {
-		opts, _ := json.Marshal(rq.Options)
-		q := Question{
-			ID:            rq.ID,
-			Type:          rq.Type,
-			Content:       rq.Content,
-			Options:       string(opts),
-			Answer:        rq.Answer,
-			Explanation:   rq.Explanation,
-			AIExplanation: rq.AIExplanation,
-		}
-		a.db.Create(&q)
-		// Init progress
-		a.db.Create(&UserProgress{QuestionID: rq.ID, Status: 0})
-	}
+	a.seedReviewCards()
+
+	a.resumeExam()
+	a.migrateLegacyExplanations()
+	return nil
 }
 
 // API Methods
 
 type QuestionView struct {
 	ID            uint     `json:"id"`
+	BankID        uint     `json:"bank_id"`
+	Number        uint     `json:"number"`
 	Type          string   `json:"type"`
 	Content       string   `json:"content"`
 	Options       []string `json:"options"`
 	UserAnswer    string   `json:"user_answer"`
 	Status        int      `json:"status"`
 	IsMarked      bool     `json:"is_marked"`
+	KeyChanged    bool     `json:"key_changed"`
+	Tags          []Tag    `json:"tags"`
+	Note          string   `json:"note"` // Markdown
 	AIExplanation string   `json:"ai_explanation"`
+	AICount       int64    `json:"ai_count"` // Number of stored AI explanations
 	Explanation   string   `json:"explanation,omitempty"`
 	CorrectAnswer string   `json:"correct_answer,omitempty"`
 }
@@ -195,17 +172,20 @@ func (a *App) GetQuestion(id uint) QuestionView {
 	var q Question
 	var p UserProgress
 	a.db.First(&q, id)
-	a.db.First(&p, id)
+	a.mine().First(&p, "question_id = ?", id)
 
 	var opts []string
 	json.Unmarshal([]byte(q.Options), &opts)
 
-	// In Mistake Mode, use session status
+	// Remember when the question was shown to measure answer duration
+	a.shownAt[id] = time.Now()
+
+	// In Mistake/Review Mode, use session status
 	status := p.Status
 	userAnswer := p.UserAnswer
 
-	if a.MistakeMode {
-		if s, ok := a.mistakeSession[id]; ok {
+	if session := a.sessionStatus(); session != nil {
+		if s, ok := session[id]; ok {
 			status = s
 			// If status is 0, user answer should be empty
 			if s == 0 {
@@ -219,14 +199,29 @@ func (a *App) GetQuestion(id uint) QuestionView {
 
 	qv := QuestionView{
 		ID:            q.ID,
+		BankID:        q.BankID,
+		Number:        q.Number,
 		Type:          q.Type,
 		Content:       q.Content,
 		Options:       opts,
 		UserAnswer:    userAnswer,
 		Status:        status,
 		IsMarked:      p.IsMarked,
+		KeyChanged:    p.KeyChanged,
+		Tags:          a.GetQuestionTags(id),
+		Note:          a.GetNote(id).Body,
 		AIExplanation: q.AIExplanation,
 	}
+	a.db.Model(&AIExplanationRecord{}).Where("question_id = ?", id).Count(&qv.AICount)
+
+	// During an exam only the answer on the paper is shown
+	if item, ok := a.inActiveExam(id); ok {
+		qv.UserAnswer = item.Answer
+		qv.Status = 0
+		qv.AIExplanation = ""
+		qv.Note = ""
+		return qv
+	}
 
 	if status > 0 {
 		qv.Explanation = q.Explanation
@@ -237,57 +232,76 @@ func (a *App) GetQuestion(id uint) QuestionView {
 }
 
 type SubmitResult struct {
-	Correct       bool   `json:"correct"`
-	Explanation   string `json:"explanation"`
-	CorrectAnswer string `json:"correct_answer"`
-	AIExplanation string `json:"ai_explanation"`
+	Correct       bool     `json:"correct"`
+	Score         float64  `json:"score"` // 0..1, partial credit for 多选题
+	Missed        []string `json:"missed"`
+	Extra         []string `json:"extra"`
+	Explanation   string   `json:"explanation"`
+	CorrectAnswer string   `json:"correct_answer"`
+	AIExplanation string   `json:"ai_explanation"`
 }
 
-func (a *App) SubmitAnswer(id uint, answer string) SubmitResult {
+func (a *App) SubmitAnswer(id uint, answer string) (SubmitResult, error) {
+	// Exam answers go onto the paper and nothing is revealed until it is submitted.
+	// The paper is checked directly so an answer after the time limit is refused.
+	if examID := a.activeExam; examID != 0 {
+		var onPaper int64
+		a.db.Model(&ExamItem{}).Where("exam_id = ? AND question_id = ?", examID, id).Count(&onPaper)
+		if onPaper > 0 {
+			return SubmitResult{}, a.SaveExamAnswer(examID, id, answer)
+		}
+	}
+
 	var q Question
-	var p UserProgress
 	a.db.First(&q, id)
-	a.db.First(&p, id)
 
-	correct := q.Answer == answer
+	grade := a.grader.Grade(q, answer)
+	correct := grade.Correct
+	if grade.Answer != "" {
+		answer = grade.Answer
+	}
 
 	status := 2
 	if correct {
 		status = 1
 	}
 
-	p.Status = status
-	p.UserAnswer = answer
-	a.db.Save(&p)
+	// Append to history, then derive the latest state from it
+	at := a.recordAttempt(id, answer, grade, a.currentMode())
+	a.refreshProgress(id)
+	a.updateReviewCard(id, correct, at.DurationMs)
 
 	if !correct {
 		// Add to mistake book
 		var mb MistakeBook
-		res := a.db.First(&mb, id)
+		res := a.mine().First(&mb, "question_id = ?", id)
 		if res.Error != nil {
-			a.db.Create(&MistakeBook{QuestionID: id, Count: 1})
+			a.db.Create(&MistakeBook{ProfileID: a.activeProfile, QuestionID: id, Count: 1})
 		} else {
 			mb.Count++
 			a.db.Save(&mb)
 		}
 	}
 
-	// Update session if in mistake mode
-	if a.MistakeMode {
-		a.mistakeSession[id] = status
+	// Update session if in mistake/review mode
+	if session := a.sessionStatus(); session != nil {
+		session[id] = status
 	}
 
 	return SubmitResult{
 		Correct:       correct,
+		Score:         grade.Score,
+		Missed:        grade.Missed,
+		Extra:         grade.Extra,
 		Explanation:   q.Explanation,
 		CorrectAnswer: q.Answer,
 		AIExplanation: q.AIExplanation,
-	}
+	}, nil
 }
 
 func (a *App) ToggleMark(id uint) bool {
-	var p UserProgress
-	a.db.First(&p, id)
+	p := UserProgress{ProfileID: a.activeProfile, QuestionID: id}
+	a.mine().First(&p, "question_id = ?", id)
 	p.IsMarked = !p.IsMarked
 	a.db.Save(&p)
 	return p.IsMarked
@@ -295,40 +309,65 @@ func (a *App) ToggleMark(id uint) bool {
 
 type GridItem struct {
 	ID       uint `json:"id"`
+	Number   uint `json:"number"`
 	Status   int  `json:"status"`
 	IsMarked bool `json:"is_marked"`
 }
 
 func (a *App) GetGrid() []GridItem {
+	// Numbers inside the active bank, used for display and ordering
+	var questions []Question
+	a.db.Select("id", "number").Where("bank_id = ? AND retired = ?", a.activeBank, false).Find(&questions)
+	numbers := make(map[uint]uint, len(questions))
+	for _, q := range questions {
+		numbers[q.ID] = q.Number
+	}
+
+	if exam := a.GetActiveExam(); exam != nil {
+		// Paper order, no status until the exam is submitted
+		grid := make([]GridItem, 0, len(exam.Items))
+		for _, item := range exam.Items {
+			grid = append(grid, GridItem{ID: item.QuestionID, Number: numbers[item.QuestionID]})
+		}
+		return grid
+	}
+
 	var progress []UserProgress
 
-	if a.MistakeMode {
+	if a.ReviewMode {
+		// Only return the due questions captured at session start
+		if len(a.reviewQueue) > 0 {
+			a.mine().Where("question_id IN ?", a.reviewQueue).Find(&progress)
+		} else {
+			progress = []UserProgress{}
+		}
+	} else if a.MistakeMode {
 		// Only return mistakes
 		var mistakes []MistakeBook
-		a.db.Find(&mistakes)
+		a.mine().Where("question_id IN (?)", a.practiceQuestionIDs()).Find(&mistakes)
 		ids := make([]uint, len(mistakes))
 		for i, m := range mistakes {
 			ids[i] = m.QuestionID
 		}
 		if len(ids) > 0 {
-			a.db.Where("question_id IN ?", ids).Find(&progress)
+			a.mine().Where("question_id IN ?", ids).Find(&progress)
 		} else {
 			progress = []UserProgress{}
 		}
 	} else {
-		a.db.Find(&progress)
+		a.mine().Where("question_id IN (?)", a.practiceQuestionIDs()).Find(&progress)
 	}
 
-	// Sort by ID
+	// Sort by number in the bank
 	sort.Slice(progress, func(i, j int) bool {
-		return progress[i].QuestionID < progress[j].QuestionID
+		return numbers[progress[i].QuestionID] < numbers[progress[j].QuestionID]
 	})
 
 	var grid []GridItem
 	for _, p := range progress {
 		status := p.Status
-		if a.MistakeMode {
-			if s, ok := a.mistakeSession[p.QuestionID]; ok {
+		if session := a.sessionStatus(); session != nil {
+			if s, ok := session[p.QuestionID]; ok {
 				status = s
 			} else {
 				status = 0
@@ -336,6 +375,7 @@ func (a *App) GetGrid() []GridItem {
 		}
 		grid = append(grid, GridItem{
 			ID:       p.QuestionID,
+			Number:   numbers[p.QuestionID],
 			Status:   status,
 			IsMarked: p.IsMarked,
 		})
@@ -348,7 +388,20 @@ func (a *App) SetMistakeMode(enable bool) {
 	if enable {
 		// Clear session when entering mistake mode
 		a.mistakeSession = make(map[uint]int)
+		a.ReviewMode = false
+	}
+}
+
+// sessionStatus returns the per-session status map of the active mode,
+// or nil in normal practice where the stored progress is shown.
+func (a *App) sessionStatus() map[uint]int {
+	if a.ReviewMode {
+		return a.reviewSession
+	}
+	if a.MistakeMode {
+		return a.mistakeSession
 	}
+	return nil
 }
 
 func (a *App) GetCorrectMistakesCount() int64 {
@@ -357,8 +410,10 @@ func (a *App) GetCorrectMistakesCount() int64 {
 	// Note: We check the GLOBAL UserProgress, because that's what we want to clean up
 	// based on the user's latest attempt (which is updated in SubmitAnswer)
 	a.db.Table("mistake_books").
-		Joins("JOIN user_progresses ON user_progresses.question_id = mistake_books.question_id").
+		Joins("JOIN user_progresses ON user_progresses.question_id = mistake_books.question_id AND user_progresses.profile_id = mistake_books.profile_id").
+		Where("mistake_books.profile_id = ?", a.activeProfile).
 		Where("user_progresses.status = ?", 1).
+		Where("mistake_books.question_id IN (?)", a.bankQuestionIDs()).
 		Count(&count)
 	return count
 }
@@ -366,12 +421,14 @@ func (a *App) GetCorrectMistakesCount() int64 {
 func (a *App) ClearCorrectMistakes() {
 	// Delete from mistake_books where corresponding user_progress status is 1
 	// SQLite doesn't support JOIN in DELETE easily, so do it in two steps or subquery
-	subQuery := a.db.Table("user_progresses").Select("question_id").Where("status = ?", 1)
-	a.db.Where("question_id IN (?)", subQuery).Delete(&MistakeBook{})
+	subQuery := a.mine().Table("user_progresses").Select("question_id").Where("status = ?", 1)
+	a.mine().Where("question_id IN (?)", subQuery).
+		Where("question_id IN (?)", a.bankQuestionIDs()).
+		Delete(&MistakeBook{})
 }
 
 func (a *App) RemoveFromMistakeBook(id uint) {
-	a.db.Delete(&MistakeBook{}, id)
+	a.mine().Where("question_id = ?", id).Delete(&MistakeBook{})
 }
 
 type Stats struct {
@@ -384,14 +441,17 @@ type Stats struct {
 func (a *App) GetStats() Stats {
 	var total, done, correct int64
 
-	if a.MistakeMode {
-		a.db.Model(&MistakeBook{}).Count(&total)
+	if a.ReviewMode {
+		total = int64(len(a.reviewQueue))
+	} else if a.MistakeMode {
+		a.mine().Model(&MistakeBook{}).Where("question_id IN (?)", a.practiceQuestionIDs()).Count(&total)
 	} else {
-		a.db.Model(&Question{}).Count(&total)
+		a.db.Model(&Question{}).Where("id IN (?)", a.practiceQuestionIDs()).Count(&total)
 	}
 
-	a.db.Model(&UserProgress{}).Where("status > 0").Count(&done)
-	a.db.Model(&UserProgress{}).Where("status = 1").Count(&correct)
+	inBank := a.mine().Model(&UserProgress{}).Where("question_id IN (?)", a.practiceQuestionIDs())
+	inBank.Session(&gorm.Session{}).Where("status > 0").Count(&done)
+	inBank.Session(&gorm.Session{}).Where("status = 1").Count(&correct)
 
 	acc := "0%"
 	if done > 0 {
@@ -417,12 +477,24 @@ func (a *App) GenerateAIExplanation(id uint, force bool) string {
 	if err := a.db.First(&q, id).Error; err != nil {
 		return "题目不存在"
 	}
+	if _, ok := a.inActiveExam(id); ok {
+		return "考试中不能查看 AI 解析"
+	}
 
 	// If not force and already has explanation, return it
 	if !force && q.AIExplanation != "" {
 		return q.AIExplanation
 	}
 
+	text, err := a.generateExplanation(context.Background(), &q)
+	if err != nil {
+		return fmt.Sprintf("AI 生成失败: %v", err)
+	}
+	return text
+}
+
+// generateExplanation asks the model for an explanation and saves it on the question.
+func (a *App) generateExplanation(ctx context.Context, q *Question) (string, error) {
 	// Construct Prompt
 	prompt := fmt.Sprintf(`
 你是一个专业的政治课助教。请对以下题目进行解析。
@@ -436,10 +508,14 @@ func (a *App) GenerateAIExplanation(id uint, force bool) string {
 `, q.Content, q.Options)
 
 	// Call API
-	resp, err := a.aiClient.CreateChatCompletion(
-		context.Background(),
+	ai := a.ai()
+	ctx, cancel := context.WithTimeout(ctx, ai.timeout())
+	defer cancel()
+	resp, err := ai.client.CreateChatCompletion(
+		ctx,
 		openai.ChatCompletionRequest{
-			Model: "kimi-k2-turbo-preview", // Updated to faster model
+			Model:       ai.Model,
+			Temperature: ai.Temperature,
 			Messages: []openai.ChatCompletionMessage{
 				{
 					Role:    openai.ChatMessageRoleSystem,
@@ -457,29 +533,22 @@ func (a *App) GenerateAIExplanation(id uint, force bool) string {
 	)
 
 	if err != nil {
-		// Fallback to standard model if custom model fails (though API usually returns error)
-		// For now just return error message
-		return fmt.Sprintf("AI 生成失败: %v", err)
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", errors.New("返回结果为空")
 	}
 
 	content := resp.Choices[0].Message.Content
 
-	// Parse JSON to ensure format and maybe reformat for display if needed
-	// But user wants specific format in the JSON.
-	// Let's just save the raw content or parsed content?
-	// The prompt asks for specific style inside the JSON fields.
-	// Let's try to parse it to verify.
+	// Keep answer and analysis apart, the raw content is stored alongside
 	var aiResp AIResponse
 	if err := json.Unmarshal([]byte(content), &aiResp); err == nil {
-		// Reformat to string for storage/display
-		finalText := fmt.Sprintf("kimi说：\n答案：%s\n解析：%s", aiResp.Answer, aiResp.Analysis)
-		q.AIExplanation = finalText
-		a.db.Save(&q)
-		return finalText
+		rec := a.newExplanationRecord(ai, q, PromptVersionJSON, aiResp.Answer, aiResp.Analysis, content, resp.Usage)
+		return a.addExplanation(q, rec), nil
 	}
 
 	// If JSON parse fails, just save the raw content if it looks like text
-	q.AIExplanation = content
-	a.db.Save(&q)
-	return content
+	rec := a.newExplanationRecord(ai, q, PromptVersionJSON, parseAIAnswer(q.Type, content), "", content, resp.Usage)
+	return a.addExplanation(q, rec), nil
 }
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+// newTestApp starts an app on a fresh database in a temporary config dir,
+// with the embedded bank synced.
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("QUIZ_DB_PATH", "")
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	a := NewApp()
+	if err := a.initDB(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { closeDB(a.db) })
+	return a
+}
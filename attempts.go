@@ -0,0 +1,188 @@
+package main
+
+import (
+	"time"
+)
+
+// Attempt is an append-only record of a single answer submission.
+// UserProgress only keeps the latest state, derived from these rows.
+type Attempt struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ProfileID  uint      `gorm:"index" json:"profile_id"`
+	QuestionID uint      `gorm:"index" json:"question_id"`
+	Answer     string    `json:"answer"`
+	Correct    bool      `json:"correct"`
+	Score      float64   `json:"score"`
+	Mode       string    `json:"mode"`        // practice, mistake, review
+	DurationMs int64     `json:"duration_ms"` // Time between showing the question and submitting
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+func (a *App) currentMode() string {
+	if a.ReviewMode {
+		return "review"
+	}
+	if a.MistakeMode {
+		return "mistake"
+	}
+	return "practice"
+}
+
+func (a *App) recordAttempt(id uint, answer string, grade Grade, mode string) Attempt {
+	var duration int64
+	if shown, ok := a.shownAt[id]; ok {
+		duration = time.Since(shown).Milliseconds()
+		delete(a.shownAt, id)
+	}
+
+	at := Attempt{
+		ProfileID:  a.activeProfile,
+		QuestionID: id,
+		Answer:     answer,
+		Correct:    grade.Correct,
+		Score:      grade.Score,
+		Mode:       mode,
+		DurationMs: duration,
+	}
+	a.db.Create(&at)
+	return at
+}
+
+// refreshProgress derives the UserProgress status of a question from its latest attempt,
+// graded against the current key. Questions without any attempt keep whatever state they already have.
+func (a *App) refreshProgress(id uint) UserProgress {
+	var p UserProgress
+	if err := a.mine().First(&p, "question_id = ?", id).Error; err != nil {
+		p = UserProgress{ProfileID: a.activeProfile, QuestionID: id}
+	}
+
+	var q Question
+	var last Attempt
+	if err := a.mine().Where("question_id = ?", id).Order("id DESC").First(&last).Error; err == nil && a.db.First(&q, id).Error == nil {
+		p.UserAnswer = last.Answer
+		p.Status = 2
+		if a.grader.Grade(q, last.Answer).Correct {
+			p.Status = 1
+		}
+
+		// Answering again after a key change clears the flag
+		var kc KeyChange
+		if a.mine().Where("question_id = ?", id).Order("id DESC").First(&kc).Error != nil || last.CreatedAt.After(kc.CreatedAt) {
+			p.KeyChanged = false
+		}
+	}
+
+	a.db.Save(&p)
+	return p
+}
+
+// RebuildProgress recomputes the UserProgress rows of the active profile from the attempt history.
+func (a *App) RebuildProgress() {
+	var ids []uint
+	a.mine().Model(&Attempt{}).Distinct("question_id").Pluck("question_id", &ids)
+	for _, id := range ids {
+		a.refreshProgress(id)
+	}
+}
+
+type QuestionHistory struct {
+	QuestionID   uint      `json:"question_id"`
+	Total        int       `json:"total"`
+	CorrectCount int       `json:"correct_count"`
+	WrongCount   int       `json:"wrong_count"`
+	Attempts     []Attempt `json:"attempts"`
+}
+
+// GetAttemptHistory returns all attempts of a question, oldest first.
+func (a *App) GetAttemptHistory(id uint) QuestionHistory {
+	var attempts []Attempt
+	a.mine().Where("question_id = ?", id).Order("id ASC").Find(&attempts)
+
+	h := QuestionHistory{
+		QuestionID: id,
+		Total:      len(attempts),
+		Attempts:   attempts,
+	}
+	for _, at := range attempts {
+		if at.Correct {
+			h.CorrectCount++
+		} else {
+			h.WrongCount++
+		}
+	}
+	return h
+}
+
+type TimelineItem struct {
+	Attempt
+	Type    string `json:"type"`
+	Content string `json:"content"`
+}
+
+// GetTimeline returns the most recent attempts across all questions, newest first.
+func (a *App) GetTimeline(limit int, offset int) []TimelineItem {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var attempts []Attempt
+	a.mine().Order("id DESC").Limit(limit).Offset(offset).Find(&attempts)
+
+	ids := make([]uint, 0, len(attempts))
+	for _, at := range attempts {
+		ids = append(ids, at.QuestionID)
+	}
+	var questions []Question
+	if len(ids) > 0 {
+		a.db.Where("id IN ?", ids).Find(&questions)
+	}
+	byID := make(map[uint]Question, len(questions))
+	for _, q := range questions {
+		byID[q.ID] = q
+	}
+
+	items := make([]TimelineItem, 0, len(attempts))
+	for _, at := range attempts {
+		q := byID[at.QuestionID]
+		items = append(items, TimelineItem{
+			Attempt: at,
+			Type:    q.Type,
+			Content: q.Content,
+		})
+	}
+	return items
+}
+
+type DailyActivity struct {
+	Day     string `json:"day"`
+	Total   int64  `json:"total"`
+	Correct int64  `json:"correct"`
+}
+
+// GetDailyActivity aggregates attempts per day for the last N days.
+func (a *App) GetDailyActivity(days int) []DailyActivity {
+	if days <= 0 {
+		days = 30
+	}
+	since := time.Now().AddDate(0, 0, -days)
+
+	var attempts []Attempt
+	a.mine().Where("created_at >= ?", since).Order("id ASC").Find(&attempts)
+
+	var result []DailyActivity
+	index := make(map[string]int)
+	for _, at := range attempts {
+		day := at.CreatedAt.Local().Format("2006-01-02")
+		i, ok := index[day]
+		if !ok {
+			i = len(result)
+			index[day] = i
+			result = append(result, DailyActivity{Day: day})
+		}
+		result[i].Total++
+		if at.Correct {
+			result[i].Correct++
+		}
+	}
+	return result
+}
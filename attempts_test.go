@@ -0,0 +1,65 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRefreshProgress(t *testing.T) {
+	a := newTestApp(t)
+	bank, err := a.ImportBank(writeBankFile(t, testBank), "测试")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var q Question
+	a.db.Where("bank_id = ? AND number = ?", bank.ID, 1).First(&q)
+
+	base := time.Now().Add(-time.Hour)
+	tests := []struct {
+		name      string
+		attempts  []string
+		key       string // Answer key when refreshing
+		changedAt int    // Key change after this many attempts, -1 for none
+		status    int
+		answer    string
+		changed   bool
+	}{
+		// Progress from before the history was kept stays as it is
+		{"no attempts", nil, "A", -1, 2, "B", true},
+		{"wrong", []string{"B"}, "A", -1, 2, "B", false},
+		{"latest wins", []string{"B", "A"}, "A", -1, 1, "A", false},
+		{"graded against the current key", []string{"A"}, "B", -1, 2, "A", false},
+		{"key changed after the last attempt", []string{"A"}, "B", 1, 2, "A", true},
+		{"answered again after a key change", []string{"B", "A"}, "A", 1, 1, "A", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a.db.Where("question_id = ?", q.ID).Delete(&Attempt{})
+			a.db.Where("question_id = ?", q.ID).Delete(&KeyChange{})
+			a.db.Save(&UserProgress{ProfileID: a.activeProfile, QuestionID: q.ID, Status: 2, UserAnswer: "B", IsMarked: true, KeyChanged: true})
+			a.db.Model(&q).Update("answer", tt.key)
+
+			for i, answer := range tt.attempts {
+				a.db.Create(&Attempt{ProfileID: a.activeProfile, QuestionID: q.ID, Answer: answer, CreatedAt: base.Add(time.Duration(i) * time.Minute)})
+			}
+			if tt.changedAt >= 0 {
+				at := base.Add(time.Duration(tt.changedAt)*time.Minute - 30*time.Second)
+				a.db.Create(&KeyChange{ProfileID: a.activeProfile, QuestionID: q.ID, CreatedAt: at})
+			}
+
+			p := a.refreshProgress(q.ID)
+			var stored UserProgress
+			a.mine().First(&stored, "question_id = ?", q.ID)
+			if stored != p {
+				t.Errorf("stored %+v; returned %+v", stored, p)
+			}
+			if p.Status != tt.status || p.UserAnswer != tt.answer || p.KeyChanged != tt.changed {
+				t.Errorf("got status %d, answer %q, key changed %v; want %d, %q, %v",
+					p.Status, p.UserAnswer, p.KeyChanged, tt.status, tt.answer, tt.changed)
+			}
+			if !p.IsMarked {
+				t.Error("mark was lost")
+			}
+		})
+	}
+}
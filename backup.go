@@ -0,0 +1,524 @@
+package main
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"gorm.io/gorm"
+)
+
+// A backup is a zip with manifest.json and data.json. It holds the learning
+// data of the active profile and the AI explanations, but not the banks.
+const (
+	backupFormat  = "quiz-backup"
+	backupVersion = 1
+)
+
+const (
+	BackupMerge   = "merge"   // Keep local data and add what is missing or newer
+	BackupReplace = "replace" // Drop the profile's data and take the backup as is
+)
+
+type BackupManifest struct {
+	Format    string    `json:"format"`
+	Version   int       `json:"version"`
+	Profile   string    `json:"profile"`
+	Questions int       `json:"questions"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// questionRef identifies a question across installs: by content hash first,
+// then by ID if bank and number still agree.
+type questionRef struct {
+	ID     uint   `json:"id"`
+	Bank   string `json:"bank"`
+	Number uint   `json:"number"`
+	Hash   string `json:"hash"`
+}
+
+type backupQuestion struct {
+	Ref          questionRef           `json:"ref"`
+	Status       int                   `json:"status"`
+	UserAnswer   string                `json:"user_answer"`
+	IsMarked     bool                  `json:"is_marked"`
+	Mistakes     int                   `json:"mistakes"` // Mistake book count, 0 if not in it
+	Note         *Note                 `json:"note,omitempty"`
+	Review       *ReviewCard           `json:"review,omitempty"`
+	Attempts     []Attempt             `json:"attempts,omitempty"`
+	Explanations []AIExplanationRecord `json:"explanations,omitempty"`
+}
+
+func (b backupQuestion) empty() bool {
+	return b.Status == 0 && b.UserAnswer == "" && !b.IsMarked && b.Mistakes == 0 &&
+		b.Note == nil && b.Review == nil && len(b.Attempts) == 0 && len(b.Explanations) == 0
+}
+
+type BackupSummary struct {
+	Strategy     string `json:"strategy"`
+	Questions    int    `json:"questions"`
+	Unmatched    int    `json:"unmatched"` // Questions not found in any bank
+	Attempts     int    `json:"attempts"`
+	Notes        int    `json:"notes"`
+	Explanations int    `json:"explanations"`
+}
+
+// ExportBackup writes the active profile's learning data to a backup file.
+func (a *App) ExportBackup(path string) (BackupManifest, error) {
+	var questions []Question
+	a.db.Find(&questions)
+	var banks []Bank
+	a.db.Find(&banks)
+	bankNames := make(map[uint]string, len(banks))
+	for _, b := range banks {
+		bankNames[b.ID] = b.Name
+	}
+
+	var progress []UserProgress
+	a.mine().Find(&progress)
+	progressByID := make(map[uint]UserProgress, len(progress))
+	for _, p := range progress {
+		progressByID[p.QuestionID] = p
+	}
+	var mistakes []MistakeBook
+	a.mine().Find(&mistakes)
+	mistakesByID := make(map[uint]int, len(mistakes))
+	for _, m := range mistakes {
+		mistakesByID[m.QuestionID] = m.Count
+	}
+	var notes []Note
+	a.mine().Find(&notes)
+	notesByID := make(map[uint]Note, len(notes))
+	for _, n := range notes {
+		notesByID[n.QuestionID] = n
+	}
+	var cards []ReviewCard
+	a.mine().Find(&cards)
+	cardsByID := make(map[uint]ReviewCard, len(cards))
+	for _, c := range cards {
+		cardsByID[c.QuestionID] = c
+	}
+	var attempts []Attempt
+	a.mine().Order("id ASC").Find(&attempts)
+	attemptsByID := make(map[uint][]Attempt)
+	for _, at := range attempts {
+		attemptsByID[at.QuestionID] = append(attemptsByID[at.QuestionID], at)
+	}
+	var records []AIExplanationRecord
+	a.db.Order("id ASC").Find(&records)
+	recordsByID := make(map[uint][]AIExplanationRecord)
+	for _, r := range records {
+		recordsByID[r.QuestionID] = append(recordsByID[r.QuestionID], r)
+	}
+
+	var data []backupQuestion
+	for _, q := range questions {
+		p := progressByID[q.ID]
+		entry := backupQuestion{
+			Ref:          questionRef{ID: q.ID, Bank: bankNames[q.BankID], Number: q.Number, Hash: q.ContentHash},
+			Status:       p.Status,
+			UserAnswer:   p.UserAnswer,
+			IsMarked:     p.IsMarked,
+			Mistakes:     mistakesByID[q.ID],
+			Attempts:     attemptsByID[q.ID],
+			Explanations: recordsByID[q.ID],
+		}
+		if n, ok := notesByID[q.ID]; ok {
+			entry.Note = &n
+		}
+		if c, ok := cardsByID[q.ID]; ok {
+			entry.Review = &c
+		}
+		if !entry.empty() {
+			data = append(data, entry)
+		}
+	}
+
+	manifest := BackupManifest{
+		Format:    backupFormat,
+		Version:   backupVersion,
+		Profile:   a.GetActiveProfile().Name,
+		Questions: len(data),
+		CreatedAt: time.Now(),
+	}
+	if err := writeBackup(path, manifest, data); err != nil {
+		return BackupManifest{}, fmt.Errorf("写入备份失败: %w", err)
+	}
+	return manifest, nil
+}
+
+func writeBackup(path string, manifest BackupManifest, data []backupQuestion) error {
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	zw := zip.NewWriter(f)
+	write := func(name string, v interface{}) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	}
+	err = write("manifest.json", manifest)
+	if err == nil {
+		err = write("data.json", data)
+	}
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readBackup opens a backup and checks its format before anything is imported.
+func readBackup(path string) (BackupManifest, []backupQuestion, error) {
+	var manifest BackupManifest
+	var data []backupQuestion
+
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return manifest, nil, errors.New("不是有效的备份文件")
+	}
+	defer zr.Close()
+
+	read := func(name string, v interface{}) error {
+		f, err := zr.Open(name)
+		if err != nil {
+			return fmt.Errorf("备份文件缺少 %s", name)
+		}
+		defer f.Close()
+		raw, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(raw, v); err != nil {
+			return fmt.Errorf("%s 格式错误: %w", name, err)
+		}
+		return nil
+	}
+	if err := read("manifest.json", &manifest); err != nil {
+		return manifest, nil, err
+	}
+	if manifest.Format != backupFormat {
+		return manifest, nil, errors.New("不是有效的备份文件")
+	}
+	if manifest.Version < 1 || manifest.Version > backupVersion {
+		return manifest, nil, fmt.Errorf("不支持的备份版本 %d，请升级应用", manifest.Version)
+	}
+	if err := read("data.json", &data); err != nil {
+		return manifest, nil, err
+	}
+
+	for i, entry := range data {
+		if entry.Ref.Hash == "" && entry.Ref.ID == 0 {
+			return manifest, nil, fmt.Errorf("第 %d 条记录缺少题目标识", i+1)
+		}
+		if entry.Status < 0 || entry.Status > 2 {
+			return manifest, nil, fmt.Errorf("第 %d 条记录的答题状态无效", i+1)
+		}
+		if entry.Mistakes < 0 {
+			return manifest, nil, fmt.Errorf("第 %d 条记录的错题次数无效", i+1)
+		}
+	}
+	return manifest, data, nil
+}
+
+// questionResolver maps backup references to questions of this install.
+type questionResolver struct {
+	byHash map[string]Question
+	byID   map[uint]Question
+	banks  map[uint]string
+}
+
+func (a *App) newQuestionResolver() questionResolver {
+	r := questionResolver{
+		byHash: make(map[string]Question),
+		byID:   make(map[uint]Question),
+		banks:  make(map[uint]string),
+	}
+	var banks []Bank
+	a.db.Find(&banks)
+	for _, b := range banks {
+		r.banks[b.ID] = b.Name
+	}
+	var questions []Question
+	a.db.Order("retired ASC, id ASC").Find(&questions)
+	for _, q := range questions {
+		r.byID[q.ID] = q
+		// Prefer the live copy when a retired question has the same content
+		if _, ok := r.byHash[q.ContentHash]; !ok && q.ContentHash != "" {
+			r.byHash[q.ContentHash] = q
+		}
+	}
+	return r
+}
+
+func (r questionResolver) resolve(ref questionRef) (Question, bool) {
+	if q, ok := r.byHash[ref.Hash]; ok && ref.Hash != "" {
+		return q, true
+	}
+	if q, ok := r.byID[ref.ID]; ok && q.Number == ref.Number && r.banks[q.BankID] == ref.Bank {
+		return q, true
+	}
+	return Question{}, false
+}
+
+// ImportBackup restores a backup into the active profile with the merge or
+// replace strategy. Replace drops the profile's learning data first; AI
+// explanations are shared by all profiles and are only ever added to.
+func (a *App) ImportBackup(path string, strategy string) (BackupSummary, error) {
+	if strategy == "" {
+		strategy = BackupMerge
+	}
+	if strategy != BackupMerge && strategy != BackupReplace {
+		return BackupSummary{}, errors.New("未知的导入方式: " + strategy)
+	}
+	if a.activeExam != 0 {
+		return BackupSummary{}, errors.New("考试进行中，不能导入备份")
+	}
+
+	_, data, err := readBackup(path)
+	if err != nil {
+		return BackupSummary{}, err
+	}
+
+	summary := BackupSummary{Strategy: strategy, Questions: len(data)}
+	resolver := a.newQuestionResolver()
+	profileID := a.activeProfile
+	replace := strategy == BackupReplace
+	var withAttempts, withExplanations []uint
+
+	err = a.db.Transaction(func(tx *gorm.DB) error {
+		if replace {
+			if err := tx.Model(&UserProgress{}).Where("profile_id = ?", profileID).
+				Updates(map[string]interface{}{"status": 0, "user_answer": "", "is_marked": false, "key_changed": false}).Error; err != nil {
+				return err
+			}
+			for _, model := range []interface{}{&MistakeBook{}, &ReviewCard{}, &Note{}, &Attempt{}} {
+				if err := tx.Where("profile_id = ?", profileID).Delete(model).Error; err != nil {
+					return err
+				}
+			}
+		}
+
+		for _, entry := range data {
+			q, ok := resolver.resolve(entry.Ref)
+			if !ok {
+				summary.Unmatched++
+				continue
+			}
+
+			// Progress and marks
+			p := UserProgress{ProfileID: profileID, QuestionID: q.ID}
+			tx.Where("profile_id = ? AND question_id = ?", profileID, q.ID).First(&p)
+			if replace || (p.Status == 0 && entry.Status > 0) {
+				p.Status = entry.Status
+				p.UserAnswer = entry.UserAnswer
+			}
+			p.IsMarked = entry.IsMarked || (!replace && p.IsMarked)
+			if err := tx.Save(&p).Error; err != nil {
+				return err
+			}
+
+			if entry.Mistakes > 0 {
+				mb := MistakeBook{ProfileID: profileID, QuestionID: q.ID}
+				tx.Where("profile_id = ? AND question_id = ?", profileID, q.ID).First(&mb)
+				if entry.Mistakes > mb.Count {
+					mb.Count = entry.Mistakes
+				}
+				if err := tx.Save(&mb).Error; err != nil {
+					return err
+				}
+			}
+
+			if entry.Note != nil {
+				var local Note
+				exists := tx.Where("profile_id = ? AND question_id = ?", profileID, q.ID).First(&local).Error == nil
+				if !exists || entry.Note.UpdatedAt.After(local.UpdatedAt) {
+					// Delete and create so the timestamps of the backup are kept
+					tx.Where("profile_id = ? AND question_id = ?", profileID, q.ID).Delete(&Note{})
+					note := *entry.Note
+					note.ProfileID, note.QuestionID = profileID, q.ID
+					if err := tx.Create(&note).Error; err != nil {
+						return err
+					}
+					summary.Notes++
+				}
+			}
+
+			if entry.Review != nil {
+				var local ReviewCard
+				exists := tx.Where("profile_id = ? AND question_id = ?", profileID, q.ID).First(&local).Error == nil
+				if !exists || entry.Review.LastReviewed.After(local.LastReviewed) {
+					card := *entry.Review
+					card.ProfileID, card.QuestionID = profileID, q.ID
+					if err := tx.Save(&card).Error; err != nil {
+						return err
+					}
+				}
+			}
+
+			if len(entry.Attempts) > 0 {
+				n, err := importAttempts(tx, profileID, q.ID, entry.Attempts)
+				if err != nil {
+					return err
+				}
+				summary.Attempts += n
+				withAttempts = append(withAttempts, q.ID)
+			}
+
+			if len(entry.Explanations) > 0 {
+				n, err := importExplanations(tx, q.ID, entry.Explanations, replace)
+				if err != nil {
+					return err
+				}
+				summary.Explanations += n
+				withExplanations = append(withExplanations, q.ID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return BackupSummary{}, fmt.Errorf("导入备份失败: %w", err)
+	}
+
+	// Derived state is rebuilt outside the transaction
+	for _, id := range withAttempts {
+		a.refreshProgress(id)
+	}
+	for _, id := range withExplanations {
+		var q Question
+		if a.db.First(&q, id).Error == nil {
+			a.refreshAIExplanation(&q)
+		}
+	}
+	a.rebuildSearchIndex()
+	a.mistakeSession = make(map[uint]int)
+	a.reviewSession = make(map[uint]int)
+	return summary, nil
+}
+
+// importAttempts adds attempts that are not in the history yet.
+func importAttempts(tx *gorm.DB, profileID uint, questionID uint, attempts []Attempt) (int, error) {
+	var local []Attempt
+	tx.Where("profile_id = ? AND question_id = ?", profileID, questionID).Find(&local)
+	seen := make(map[string]bool, len(local))
+	for _, at := range local {
+		seen[at.CreatedAt.UTC().Format(time.RFC3339Nano)+"\x00"+at.Answer] = true
+	}
+
+	added := 0
+	for _, at := range attempts {
+		key := at.CreatedAt.UTC().Format(time.RFC3339Nano) + "\x00" + at.Answer
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		at.ID = 0
+		at.ProfileID, at.QuestionID = profileID, questionID
+		if err := tx.Create(&at).Error; err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, nil
+}
+
+// importExplanations adds AI explanations that are not stored yet. The records
+// are shared by every profile, so none are deleted: replacing only lets the
+// backup's pinned explanation win, while merging keeps a local pin.
+func importExplanations(tx *gorm.DB, questionID uint, records []AIExplanationRecord, replace bool) (int, error) {
+	var local []AIExplanationRecord
+	tx.Where("question_id = ?", questionID).Find(&local)
+	stored := make(map[string]uint, len(local))
+	pinned := false
+	for _, r := range local {
+		stored[r.Model+"\x00"+r.Raw] = r.ID
+		pinned = pinned || r.Preferred
+	}
+
+	if replace && pinned {
+		for _, r := range records {
+			if !r.Preferred {
+				continue
+			}
+			if err := tx.Model(&AIExplanationRecord{}).Where("question_id = ?", questionID).Update("preferred", false).Error; err != nil {
+				return 0, err
+			}
+			pinned = false
+			break
+		}
+	}
+
+	added := 0
+	for _, r := range records {
+		key := r.Model + "\x00" + r.Raw
+		if id, ok := stored[key]; ok {
+			if r.Preferred && !pinned {
+				if err := tx.Model(&AIExplanationRecord{}).Where("id = ?", id).Update("preferred", true).Error; err != nil {
+					return added, err
+				}
+				pinned = true
+			}
+			continue
+		}
+		r.ID = 0
+		r.QuestionID = questionID
+		if pinned {
+			r.Preferred = false
+		}
+		pinned = pinned || r.Preferred
+		if err := tx.Create(&r).Error; err != nil {
+			return added, err
+		}
+		stored[key] = r.ID
+		added++
+	}
+	return added, nil
+}
+
+// ExportBackupDialog asks where to save the backup and writes it.
+// An empty path is returned if the dialog was cancelled.
+func (a *App) ExportBackupDialog() (string, error) {
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "导出备份",
+		DefaultFilename: "quiz-backup-" + time.Now().Format("20060102") + ".zip",
+		Filters:         []runtime.FileFilter{{DisplayName: "备份文件 (*.zip)", Pattern: "*.zip"}},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	if filepath.Ext(path) == "" {
+		path += ".zip"
+	}
+	_, err = a.ExportBackup(path)
+	return path, err
+}
+
+// ImportBackupDialog lets the user pick a backup file and imports it.
+func (a *App) ImportBackupDialog(strategy string) (BackupSummary, error) {
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title:   "导入备份",
+		Filters: []runtime.FileFilter{{DisplayName: "备份文件 (*.zip)", Pattern: "*.zip"}},
+	})
+	if err != nil || path == "" {
+		return BackupSummary{}, err
+	}
+	return a.ImportBackup(path, strategy)
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// bankQuestions returns the first n live questions of the active bank.
+func bankQuestions(t *testing.T, a *App, n int) []Question {
+	t.Helper()
+	var questions []Question
+	a.db.Where("bank_id = ? AND retired = ?", a.activeBank, false).Order("id ASC").Limit(n).Find(&questions)
+	if len(questions) != n {
+		t.Fatalf("active bank has %d questions; want %d", len(questions), n)
+	}
+	return questions
+}
+
+func TestImportBackupMatching(t *testing.T) {
+	a := newTestApp(t)
+	qs := bankQuestions(t, a, 3)
+	var bank Bank
+	a.db.First(&bank, a.activeBank)
+
+	data := []backupQuestion{
+		// Content hash wins over a stale ID
+		{Ref: questionRef{ID: 99999, Hash: qs[0].ContentHash}, IsMarked: true},
+		// Unknown hash, but bank and number agree with the ID
+		{Ref: questionRef{ID: qs[1].ID, Bank: bank.Name, Number: qs[1].Number, Hash: "unknown"}, IsMarked: true},
+		// Same ID in another bank
+		{Ref: questionRef{ID: qs[2].ID, Bank: "别的题库", Number: qs[2].Number}, IsMarked: true},
+		// Same ID, but the number moved
+		{Ref: questionRef{ID: qs[2].ID, Bank: bank.Name, Number: qs[2].Number + 1}, IsMarked: true},
+	}
+	path := filepath.Join(t.TempDir(), "backup.zip")
+	if err := writeBackup(path, BackupManifest{Format: backupFormat, Version: backupVersion}, data); err != nil {
+		t.Fatal(err)
+	}
+
+	summary, err := a.ImportBackup(path, BackupMerge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Questions != 4 || summary.Unmatched != 2 {
+		t.Errorf("summary = %+v; want 4 questions, 2 unmatched", summary)
+	}
+	for i, want := range []bool{true, true, false} {
+		var p UserProgress
+		a.mine().First(&p, "question_id = ?", qs[i].ID)
+		if p.IsMarked != want {
+			t.Errorf("question %d marked = %v; want %v", qs[i].ID, p.IsMarked, want)
+		}
+	}
+}
+
+func TestImportBackupStrategy(t *testing.T) {
+	note := time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		strategy     string
+		status       int
+		answer       string
+		marked       bool // Whether the other, locally marked question stays marked
+		mistakes     int
+		backupNote   bool // Whether the older backup note replaced the local one
+		localMistake bool // Whether the other question stays in the mistake book
+	}{
+		{BackupMerge, 1, "A", true, 3, false, true},
+		{BackupReplace, 2, "B", false, 3, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			a := newTestApp(t)
+			qs := bankQuestions(t, a, 2)
+			id, other := qs[0].ID, qs[1].ID
+
+			a.mine().Model(&UserProgress{}).Where("question_id = ?", id).Updates(map[string]interface{}{"status": 1, "user_answer": "A"})
+			a.mine().Model(&UserProgress{}).Where("question_id = ?", other).Update("is_marked", true)
+			a.db.Create(&MistakeBook{ProfileID: a.activeProfile, QuestionID: id, Count: 1})
+			a.db.Create(&MistakeBook{ProfileID: a.activeProfile, QuestionID: other, Count: 1})
+			a.db.Create(&Note{ProfileID: a.activeProfile, QuestionID: id, Body: "本地", CreatedAt: note, UpdatedAt: note.Add(time.Hour)})
+
+			data := []backupQuestion{{
+				Ref:        questionRef{Hash: qs[0].ContentHash},
+				Status:     2,
+				UserAnswer: "B",
+				Mistakes:   3,
+				Note:       &Note{Body: "备份", CreatedAt: note, UpdatedAt: note},
+			}}
+			path := filepath.Join(t.TempDir(), "backup.zip")
+			if err := writeBackup(path, BackupManifest{Format: backupFormat, Version: backupVersion}, data); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := a.ImportBackup(path, tt.strategy); err != nil {
+				t.Fatal(err)
+			}
+
+			var p UserProgress
+			a.mine().First(&p, "question_id = ?", id)
+			if p.Status != tt.status || p.UserAnswer != tt.answer {
+				t.Errorf("progress = %d %q; want %d %q", p.Status, p.UserAnswer, tt.status, tt.answer)
+			}
+			var op UserProgress
+			a.mine().First(&op, "question_id = ?", other)
+			if op.IsMarked != tt.marked {
+				t.Errorf("other question marked = %v; want %v", op.IsMarked, tt.marked)
+			}
+			var mb MistakeBook
+			a.mine().First(&mb, "question_id = ?", id)
+			if mb.Count != tt.mistakes {
+				t.Errorf("mistake count = %d; want %d", mb.Count, tt.mistakes)
+			}
+			var count int64
+			a.mine().Model(&MistakeBook{}).Where("question_id = ?", other).Count(&count)
+			if (count == 1) != tt.localMistake {
+				t.Errorf("local mistake kept = %v; want %v", count == 1, tt.localMistake)
+			}
+			var n Note
+			a.mine().First(&n, "question_id = ?", id)
+			if want := map[bool]string{true: "备份", false: "本地"}[tt.backupNote]; n.Body != want {
+				t.Errorf("note = %q; want %q", n.Body, want)
+			}
+		})
+	}
+}
+
+// TestBackupRoundTrip restores a backup into a second profile.
+func TestBackupRoundTrip(t *testing.T) {
+	a := newTestApp(t)
+	qs := bankQuestions(t, a, 2)
+	id := qs[0].ID
+
+	a.mine().Model(&UserProgress{}).Where("question_id = ?", qs[1].ID).Update("is_marked", true)
+	a.db.Create(&MistakeBook{ProfileID: a.activeProfile, QuestionID: id, Count: 2})
+	a.db.Create(&Attempt{ProfileID: a.activeProfile, QuestionID: id, Answer: "X", CreatedAt: time.Now().Add(-time.Hour)})
+	a.refreshProgress(id)
+	if _, err := a.SaveNote(id, "笔记"); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "backup.zip")
+	manifest, err := a.ExportBackup(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Questions != 2 {
+		t.Errorf("manifest has %d questions; want 2", manifest.Questions)
+	}
+
+	profile, err := a.CreateProfile("小红")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SwitchProfile(profile.ID); err != nil {
+		t.Fatal(err)
+	}
+	summary, err := a.ImportBackup(path, BackupMerge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Unmatched != 0 || summary.Attempts != 1 || summary.Notes != 1 {
+		t.Errorf("summary = %+v; want 1 attempt, 1 note, nothing unmatched", summary)
+	}
+	var p UserProgress
+	a.mine().First(&p, "question_id = ?", id)
+	if p.Status != 2 || p.UserAnswer != "X" {
+		t.Errorf("progress = %d %q; want 2 \"X\"", p.Status, p.UserAnswer)
+	}
+	var marked UserProgress
+	a.mine().First(&marked, "question_id = ?", qs[1].ID)
+	if !marked.IsMarked {
+		t.Error("marked question lost")
+	}
+
+	// Importing again adds nothing
+	summary, err = a.ImportBackup(path, BackupMerge)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Attempts != 0 || summary.Notes != 0 {
+		t.Errorf("second import = %+v; want no new attempts or notes", summary)
+	}
+}
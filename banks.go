@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"gorm.io/gorm"
+
+	"quiz-app/internal/quizbank"
+)
+
+// The embedded questions.json is always bank 1
+const defaultBankID = 1
+
+// Bank is a question bank. Question.ID stays globally unique so progress
+// tables need no bank column; Question.Number is the ID inside the bank file.
+type Bank struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `json:"name"`
+	Source    string    `json:"source"`  // "embedded" or the imported file path
+	Version   string    `json:"version"` // Hash of the last synced file
+	Mapping   string    `json:"-"`       // Column mapping of spreadsheet banks
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func parseBankJSON(data []byte) ([]quizbank.Question, error) {
+	var raws []quizbank.Question
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return nil, fmt.Errorf("题库文件格式错误: %w", err)
+	}
+	seen := make(map[uint]bool, len(raws))
+	for i, rq := range raws {
+		if rq.ID == 0 {
+			return nil, fmt.Errorf("第 %d 道题缺少 id", i+1)
+		}
+		if seen[rq.ID] {
+			return nil, fmt.Errorf("题号 %d 重复", rq.ID)
+		}
+		seen[rq.ID] = true
+		if strings.TrimSpace(rq.Content) == "" {
+			return nil, fmt.Errorf("题号 %d 缺少题目内容", rq.ID)
+		}
+	}
+	return raws, nil
+}
+
+// toQuestion converts a bank file question to a row of the bank.
+func toQuestion(rq quizbank.Question, bankID uint) Question {
+	opts, _ := json.Marshal(rq.Options)
+	q := Question{
+		BankID:        bankID,
+		Number:        rq.ID,
+		Type:          rq.Type,
+		Content:       rq.Content,
+		Options:       string(opts),
+		Answer:        rq.Answer,
+		Explanation:   rq.Explanation,
+		AIExplanation: rq.AIExplanation,
+	}
+	q.ContentHash = q.hash()
+	return q
+}
+
+// insertQuestions adds questions to a bank along with an empty progress row per profile.
+func insertQuestions(tx *gorm.DB, bankID uint, raws []quizbank.Question) error {
+	var profiles []uint
+	tx.Model(&Profile{}).Pluck("id", &profiles)
+	for _, rq := range raws {
+		q := toQuestion(rq, bankID)
+		if err := tx.Create(&q).Error; err != nil {
+			return err
+		}
+		for _, profileID := range profiles {
+			if err := tx.Create(&UserProgress{ProfileID: profileID, QuestionID: q.ID, Status: 0}).Error; err != nil {
+				return err
+			}
+		}
+		if err := syncQuestionTags(tx, bankID, q.ID, rq.Tags); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateBanks creates the default bank and assigns questions from before
+// banks existed to it, keeping their IDs as numbers.
+func (a *App) migrateBanks() {
+	var bank Bank
+	if err := a.db.First(&bank, defaultBankID).Error; err != nil {
+		a.db.Create(&Bank{ID: defaultBankID, Name: "习概题库", Source: "embedded"})
+	}
+	a.db.Model(&Question{}).Where("bank_id = 0 OR bank_id IS NULL").
+		Updates(map[string]interface{}{"bank_id": defaultBankID, "number": gorm.Expr("id")})
+
+	a.activeBank = defaultBankID
+	if id, err := strconv.Atoi(a.getSetting("active_bank", "")); err == nil {
+		if a.db.First(&bank, id).Error == nil {
+			a.activeBank = uint(id)
+		}
+	}
+}
+
+// bankQuestionIDs is a subquery of the question IDs in the active bank.
+func (a *App) bankQuestionIDs() *gorm.DB {
+	return a.db.Model(&Question{}).Select("id").Where("bank_id = ? AND retired = ?", a.activeBank, false)
+}
+
+type BankView struct {
+	Bank
+	Count  int64 `json:"count"`
+	Active bool  `json:"active"`
+}
+
+func (a *App) ListBanks() []BankView {
+	var banks []Bank
+	a.db.Order("id ASC").Find(&banks)
+
+	views := make([]BankView, 0, len(banks))
+	for _, b := range banks {
+		v := BankView{Bank: b, Active: b.ID == a.activeBank}
+		a.db.Model(&Question{}).Where("bank_id = ? AND retired = ?", b.ID, false).Count(&v.Count)
+		views = append(views, v)
+	}
+	return views
+}
+
+func (a *App) GetActiveBank() uint {
+	return a.activeBank
+}
+
+// SetActiveBank switches banks and ends any mistake or review session.
+func (a *App) SetActiveBank(id uint) error {
+	var bank Bank
+	if err := a.db.First(&bank, id).Error; err != nil {
+		return errors.New("题库不存在")
+	}
+	if a.activeExam != 0 {
+		return errors.New("考试进行中，不能切换题库")
+	}
+
+	a.activeBank = id
+	a.activeTag = 0
+	a.setSetting("active_bank", strconv.Itoa(int(id)))
+	a.MistakeMode = false
+	a.ReviewMode = false
+	a.mistakeSession = make(map[uint]int)
+	a.reviewSession = make(map[uint]int)
+	a.reviewQueue = nil
+	return nil
+}
+
+// ImportBank reads a JSON bank file in the questions.json format and adds it as a new bank.
+func (a *App) ImportBank(path string, name string) (Bank, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Bank{}, fmt.Errorf("读取题库文件失败: %w", err)
+	}
+	raws, err := parseBankJSON(data)
+	if err != nil {
+		return Bank{}, err
+	}
+	if len(raws) == 0 {
+		return Bank{}, errors.New("题库文件中没有题目")
+	}
+
+	if strings.TrimSpace(name) == "" {
+		name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	bank := Bank{Name: name, Source: path}
+	if err := a.db.Create(&bank).Error; err != nil {
+		return Bank{}, err
+	}
+	if _, err := a.syncBank(bank.ID, data); err != nil {
+		a.db.Delete(&bank)
+		return Bank{}, err
+	}
+	a.db.First(&bank, bank.ID)
+	return bank, nil
+}
+
+// ImportBankDialog lets the user pick a bank file and imports it.
+// An empty bank is returned if the dialog was cancelled.
+func (a *App) ImportBankDialog() (Bank, error) {
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title:   "导入题库",
+		Filters: []runtime.FileFilter{{DisplayName: "题库文件 (*.json)", Pattern: "*.json"}},
+	})
+	if err != nil || path == "" {
+		return Bank{}, err
+	}
+	return a.ImportBank(path, "")
+}
+
+func (a *App) RenameBank(id uint, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("题库名称不能为空")
+	}
+	return a.db.Model(&Bank{}).Where("id = ?", id).Update("name", name).Error
+}
+
+// DeleteBank removes an imported bank with its questions and all learning data about them.
+func (a *App) DeleteBank(id uint) error {
+	if id == defaultBankID {
+		return errors.New("不能删除内置题库")
+	}
+	if id == a.activeBank {
+		return errors.New("不能删除正在使用的题库")
+	}
+
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		ids := tx.Model(&Question{}).Select("id").Where("bank_id = ?", id)
+
+		// Exams drawn from this bank can't be reviewed without their questions
+		var exams []uint
+		tx.Model(&ExamItem{}).Distinct().Where("question_id IN (?)", ids).Pluck("exam_id", &exams)
+		if len(exams) > 0 {
+			if err := tx.Where("exam_id IN ?", exams).Delete(&ExamItem{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&ExamSession{}, exams).Error; err != nil {
+				return err
+			}
+		}
+
+		for _, model := range []interface{}{
+			&UserProgress{}, &MistakeBook{}, &Attempt{}, &ReviewCard{},
+			&AIExplanationRecord{}, &ChatMessage{}, &AIJobItem{}, &QuestionTag{}, &TopicSuggestion{}, &Note{}, &KeyChange{},
+		} {
+			if err := tx.Where("question_id IN (?)", ids).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Where("bank_id = ?", id).Delete(&Question{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("bank_id = ?", id).Delete(&BankVersion{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("bank_id = ?", id).Delete(&Tag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Bank{}, id).Error
+	})
+	if err == nil {
+		a.rebuildSearchIndex()
+	}
+	return err
+}
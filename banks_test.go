@@ -0,0 +1,57 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeBankFile(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "bank.json")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const testBank = `[
+	{"id": 1, "type": "单选题", "content": "甲", "options": ["A、1", "B、2"], "answer": "A"},
+	{"id": 2, "type": "单选题", "content": "乙", "options": ["A、1", "B、2"], "answer": "B"}
+]`
+
+func TestDeleteBankRemovesQuestionRows(t *testing.T) {
+	a := newTestApp(t)
+	bank, err := a.ImportBank(writeBankFile(t, testBank), "测试")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint
+	a.db.Model(&Question{}).Where("bank_id = ?", bank.ID).Pluck("id", &ids)
+
+	exam := ExamSession{ProfileID: a.activeProfile, Status: "submitted"}
+	a.db.Create(&exam)
+	a.db.Create(&ExamItem{ExamID: exam.ID, QuestionID: ids[0]})
+	a.db.Create(&KeyChange{ProfileID: a.activeProfile, QuestionID: ids[1]})
+
+	if err := a.DeleteBank(bank.ID); err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]int64{}
+	var n int64
+	a.db.Model(&Question{}).Where("bank_id = ?", bank.ID).Count(&n)
+	counts["questions"] = n
+	a.db.Model(&UserProgress{}).Where("question_id IN ?", ids).Count(&n)
+	counts["progress"] = n
+	a.db.Model(&KeyChange{}).Where("question_id IN ?", ids).Count(&n)
+	counts["key changes"] = n
+	a.db.Model(&ExamSession{}).Where("id = ?", exam.ID).Count(&n)
+	counts["exams"] = n
+	a.db.Model(&ExamItem{}).Where("exam_id = ?", exam.ID).Count(&n)
+	counts["exam items"] = n
+	for name, c := range counts {
+		if c != 0 {
+			t.Errorf("%d %s left", c, name)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+
+	"quiz-app/internal/quizbank"
+)
+
+// aiSettings is the part of the app's ai.json this command needs.
+type aiSettings struct {
+	BaseURL string `json:"base_url"`
+	APIKey  string `json:"api_key"`
+	Model   string `json:"model"`
+}
+
+// loadAISettings reads the app's settings file with the same defaults and
+// environment overrides as the app.
+func loadAISettings() aiSettings {
+	s := aiSettings{BaseURL: "https://api.moonshot.cn/v1", Model: "kimi-k2-turbo-preview"}
+	if dir, err := os.UserConfigDir(); err == nil {
+		if data, err := os.ReadFile(filepath.Join(dir, "quiz-app", "ai.json")); err == nil {
+			json.Unmarshal(data, &s)
+		}
+	}
+	if v := os.Getenv("QUIZ_AI_BASE_URL"); v != "" {
+		s.BaseURL = v
+	}
+	if v := os.Getenv("QUIZ_AI_MODEL"); v != "" {
+		s.Model = v
+	}
+	for _, name := range []string{"QUIZ_AI_API_KEY", "OPENAI_API_KEY", "MOONSHOT_API_KEY"} {
+		if v := os.Getenv(name); s.APIKey == "" && v != "" {
+			s.APIKey = v
+		}
+	}
+	return s
+}
+
+func runAIFixAnswers(args []string) error {
+	fs := newFlagSet("ai-fix-answers")
+	bf := addBankFlags(fs)
+	ids := fs.String("ids", "", "comma-separated question IDs, defaults to the ones found by check")
+	fs.Parse(args)
+
+	questions, err := loadBank(*bf.in)
+	if err != nil {
+		return err
+	}
+	targetIDs := make(map[uint]bool)
+	if *ids == "" {
+		for _, q := range suspicious(questions) {
+			targetIDs[q.ID] = true
+		}
+	}
+	for _, field := range strings.FieldsFunc(*ids, func(r rune) bool { return r == ',' || r == ' ' }) {
+		id, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			return fmt.Errorf("bad id %q", field)
+		}
+		targetIDs[uint(id)] = true
+	}
+	if len(targetIDs) == 0 {
+		fmt.Println("No questions to fix.")
+		return nil
+	}
+
+	s := loadAISettings()
+	if s.APIKey == "" {
+		return fmt.Errorf("no API key, set QUIZ_AI_API_KEY or configure one in the app")
+	}
+	config := openai.DefaultConfig(s.APIKey)
+	config.BaseURL = strings.TrimRight(s.BaseURL, "/")
+	client := openai.NewClientWithConfig(config)
+
+	for i := range questions {
+		if !targetIDs[questions[i].ID] {
+			continue
+		}
+		fmt.Printf("Fixing Question ID %d: %s\n", questions[i].ID, questions[i].Content)
+
+		prompt := fmt.Sprintf(`
+题目：%s
+选项：%s
+这是一道%s。请给出正确答案的选项字母，如果有多个选项，请用逗号分隔（例如：A,B,C）。
+只返回答案字母，不要包含其他文字。
+`, questions[i].Content, strings.Join(questions[i].Options, "\n"), questions[i].Type)
+
+		resp, err := client.CreateChatCompletion(
+			context.Background(),
+			openai.ChatCompletionRequest{
+				Model: s.Model,
+				Messages: []openai.ChatCompletionMessage{
+					{
+						Role:    openai.ChatMessageRoleSystem,
+						Content: "你是一个专业的政治课助教。请只返回选项字母，用逗号分隔。",
+					},
+					{
+						Role:    openai.ChatMessageRoleUser,
+						Content: prompt,
+					},
+				},
+			},
+		)
+		if err != nil || len(resp.Choices) == 0 {
+			fmt.Printf("Error fetching AI for ID %d: %v\n", questions[i].ID, err)
+			continue
+		}
+
+		letters := quizbank.AnswerLetters(questions[i].Type, resp.Choices[0].Message.Content)
+		if len(letters) == 0 {
+			fmt.Printf("No answer in reply for ID %d: %q\n", questions[i].ID, resp.Choices[0].Message.Content)
+			continue
+		}
+		newAnswer := strings.Join(letters, ",")
+		fmt.Printf("Old Answer: %s, New Answer: %s\n", questions[i].Answer, newAnswer)
+		questions[i].Answer = newAnswer
+	}
+	return bf.write(questions)
+}
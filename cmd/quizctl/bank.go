@@ -0,0 +1,135 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"quiz-app/internal/quizbank"
+)
+
+func loadBank(path string) ([]quizbank.Question, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var questions []quizbank.Question
+	if err := json.Unmarshal(data, &questions); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return questions, nil
+}
+
+func saveBank(path string, questions []quizbank.Question) error {
+	data, err := json.MarshalIndent(questions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// bankFlags are the input and output flags shared by the commands that
+// rewrite a bank.
+type bankFlags struct {
+	in     *string
+	out    *string
+	dryRun *bool
+}
+
+func addBankFlags(fs *flag.FlagSet) bankFlags {
+	return bankFlags{
+		in:     fs.String("in", "questions.json", "bank file to read"),
+		out:    fs.String("out", "", "file to write, defaults to -in"),
+		dryRun: fs.Bool("dry-run", false, "print a diff instead of writing"),
+	}
+}
+
+func (f bankFlags) outPath() string {
+	if *f.out != "" {
+		return *f.out
+	}
+	return *f.in
+}
+
+// write saves the bank, or with -dry-run prints how it differs from what
+// is at the output path now.
+func (f bankFlags) write(questions []quizbank.Question) error {
+	return writeBank(f.outPath(), questions, *f.dryRun)
+}
+
+func writeBank(path string, questions []quizbank.Question, dryRun bool) error {
+	if !dryRun {
+		if err := saveBank(path, questions); err != nil {
+			return err
+		}
+		fmt.Println("Wrote", path)
+		return nil
+	}
+	old, err := loadBank(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	n := printDiff(os.Stdout, old, questions)
+	fmt.Printf("%d questions would change in %s (dry run, nothing written)\n", n, path)
+	return nil
+}
+
+func questionFields(q quizbank.Question) [][2]string {
+	return [][2]string{
+		{"type", q.Type},
+		{"content", q.Content},
+		{"options", strings.Join(q.Options, " | ")},
+		{"answer", q.Answer},
+		{"explanation", q.Explanation},
+		{"ai_explanation", q.AIExplanation},
+		{"tags", strings.Join(q.Tags, ", ")},
+	}
+}
+
+// printDiff writes the changed fields of each question, matched by ID, and
+// returns the number of questions added, removed or changed.
+func printDiff(w io.Writer, old, next []quizbank.Question) int {
+	byID := make(map[uint]quizbank.Question, len(old))
+	for _, q := range old {
+		byID[q.ID] = q
+	}
+	changed := 0
+	seen := make(map[uint]bool, len(next))
+	for _, q := range next {
+		seen[q.ID] = true
+		prev, ok := byID[q.ID]
+		if !ok {
+			changed++
+			fmt.Fprintf(w, "@@ %d (new) @@\n", q.ID)
+			for _, f := range questionFields(q) {
+				if f[1] != "" {
+					fmt.Fprintf(w, "+%s: %s\n", f[0], f[1])
+				}
+			}
+			continue
+		}
+		before, after := questionFields(prev), questionFields(q)
+		header := false
+		for i := range after {
+			if before[i][1] == after[i][1] {
+				continue
+			}
+			if !header {
+				changed++
+				header = true
+				fmt.Fprintf(w, "@@ %d @@\n", q.ID)
+			}
+			fmt.Fprintf(w, "-%s: %s\n+%s: %s\n", before[i][0], before[i][1], after[i][0], after[i][1])
+		}
+	}
+	for _, q := range old {
+		if !seen[q.ID] {
+			changed++
+			fmt.Fprintf(w, "@@ %d (removed) @@\n-content: %s\n", q.ID, q.Content)
+		}
+	}
+	return changed
+}
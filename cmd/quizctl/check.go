@@ -0,0 +1,105 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"quiz-app/internal/quizbank"
+)
+
+// suspicious returns the multiple-choice questions with a single-letter
+// answer, usually a parsing mistake.
+func suspicious(questions []quizbank.Question) []quizbank.Question {
+	var found []quizbank.Question
+	for _, q := range questions {
+		if q.Type == "多选题" && !strings.Contains(q.Answer, ",") && len(q.Answer) == 1 {
+			found = append(found, q)
+		}
+	}
+	return found
+}
+
+func runCheck(args []string) error {
+	fs := newFlagSet("check")
+	in := fs.String("in", "questions.json", "bank file to read")
+	fs.Parse(args)
+
+	questions, err := loadBank(*in)
+	if err != nil {
+		return err
+	}
+	found := suspicious(questions)
+	for _, q := range found {
+		fmt.Printf("ID: %d, Answer: %s, Content: %s\n", q.ID, q.Answer, q.Content)
+	}
+	fmt.Printf("Total suspicious questions: %d\n", len(found))
+	return nil
+}
+
+// validateQuestion applies the checks the app makes on import plus the
+// answer checks of the grader, which expects option letters.
+func validateQuestion(q quizbank.Question) []string {
+	var problems []string
+	if strings.TrimSpace(q.Content) == "" {
+		problems = append(problems, "empty content")
+	}
+	known := false
+	for _, t := range quizbank.Types {
+		known = known || q.Type == t
+	}
+	if !known {
+		problems = append(problems, fmt.Sprintf("unknown type %q", q.Type))
+	}
+	if len(q.Options) < 2 {
+		problems = append(problems, "fewer than 2 options")
+	}
+	for i, opt := range q.Options {
+		if !strings.HasPrefix(strings.TrimSpace(opt), string(rune('A'+i))) {
+			problems = append(problems, fmt.Sprintf("option %d does not start with %c", i+1, 'A'+i))
+			break
+		}
+	}
+
+	letters := quizbank.AnswerLetters(q.Type, q.Answer)
+	if len(letters) == 0 {
+		return append(problems, fmt.Sprintf("answer %q has no option letters", q.Answer))
+	}
+	if last := letters[len(letters)-1]; int(last[0]-'A') >= len(q.Options) {
+		problems = append(problems, fmt.Sprintf("answer %s is not an option", last))
+	}
+	if q.Type != "多选题" && len(letters) > 1 {
+		problems = append(problems, fmt.Sprintf("%s with %d answers", q.Type, len(letters)))
+	}
+	return problems
+}
+
+func runValidate(args []string) error {
+	fs := newFlagSet("validate")
+	in := fs.String("in", "questions.json", "bank file to read")
+	fs.Parse(args)
+
+	questions, err := loadBank(*in)
+	if err != nil {
+		return err
+	}
+	seen := make(map[uint]bool, len(questions))
+	bad := 0
+	for i, q := range questions {
+		problems := validateQuestion(q)
+		if q.ID == 0 {
+			problems = append(problems, fmt.Sprintf("question %d has no id", i+1))
+		} else if seen[q.ID] {
+			problems = append(problems, "duplicate id")
+		}
+		seen[q.ID] = true
+		if len(problems) > 0 {
+			bad++
+			fmt.Printf("ID %d: %s\n", q.ID, strings.Join(problems, "; "))
+		}
+	}
+	fmt.Printf("%d questions, %d with problems\n", len(questions), bad)
+	if bad > 0 {
+		return fmt.Errorf("%s is not valid", *in)
+	}
+	return nil
+}
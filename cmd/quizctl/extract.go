@@ -0,0 +1,709 @@
+package main
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// readLines returns the trimmed, non-empty text lines of a txt, docx or pdf file.
+func readLines(path string) ([]string, error) {
+	var text string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".docx":
+		t, err := docxText(path)
+		if err != nil {
+			return nil, err
+		}
+		text = t
+	case ".pdf":
+		t, err := pdfText(path)
+		if err != nil {
+			return nil, err
+		}
+		text = t
+	default:
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		text = string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		// Word uses non-breaking and full-width spaces for alignment
+		line := strings.NewReplacer(" ", " ", "　", " ").Replace(scanner.Text())
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// docxText extracts the paragraphs of a Word document, one per line.
+// Automatic list numbering is not part of the text and is lost.
+func docxText(path string) (string, error) {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return "", fmt.Errorf("not a docx file: %w", err)
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.Name != "word/document.xml" {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return "", err
+		}
+		defer rc.Close()
+
+		var b strings.Builder
+		inText := false
+		dec := xml.NewDecoder(rc)
+		for {
+			tok, err := dec.Token()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", fmt.Errorf("bad document.xml: %w", err)
+			}
+			switch t := tok.(type) {
+			case xml.StartElement:
+				switch t.Name.Local {
+				case "t":
+					inText = true
+				case "tab":
+					b.WriteString("\t")
+				case "br", "cr":
+					b.WriteString("\n")
+				}
+			case xml.EndElement:
+				switch t.Name.Local {
+				case "t":
+					inText = false
+				case "p":
+					b.WriteString("\n")
+				}
+			case xml.CharData:
+				if inText {
+					b.Write(t)
+				}
+			}
+		}
+		return b.String(), nil
+	}
+	return "", fmt.Errorf("word/document.xml not found in %s", path)
+}
+
+// pdfText extracts the text of a PDF page by page. Only text-based PDFs
+// with Flate-compressed or plain streams are supported; scanned pages have
+// no text and need OCR first.
+func pdfText(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
+		return "", fmt.Errorf("%s is not a PDF file", path)
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return "", fmt.Errorf("encrypted PDFs are not supported")
+	}
+	doc := parsePDF(data)
+
+	var b strings.Builder
+	for _, page := range doc.pages() {
+		b.WriteString(doc.pageText(page))
+		b.WriteString("\n")
+	}
+	if strings.TrimSpace(b.String()) == "" {
+		return "", fmt.Errorf("no text found in %s, it may be a scanned document", path)
+	}
+	return b.String(), nil
+}
+
+type pdfObject struct {
+	dict   string
+	stream []byte // Decoded, nil if none
+}
+
+type pdfDoc struct {
+	objects map[int]pdfObject
+	root    int
+	cmaps   map[int]*toUnicode // By font object
+}
+
+type pdfPage struct {
+	dict      string
+	resources string
+}
+
+var (
+	reObjStart = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+	reRef      = regexp.MustCompile(`^(\d+)\s+\d+\s+R`)
+	reRoot     = regexp.MustCompile(`/Root\s+(\d+)\s+\d+\s+R`)
+)
+
+func parsePDF(data []byte) *pdfDoc {
+	doc := &pdfDoc{objects: make(map[int]pdfObject), cmaps: make(map[int]*toUnicode)}
+	var objStreams []pdfObject
+
+	pos := 0
+	for {
+		loc := reObjStart.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		start := pos + loc[1]
+		end := bytes.Index(data[start:], []byte("endobj"))
+		if end < 0 {
+			break
+		}
+		body := data[start : start+end]
+		obj := pdfObject{dict: string(body)}
+
+		if s := bytes.Index(body, []byte("stream")); s >= 0 && !bytes.Contains(body[:s], []byte("endstream")) {
+			obj.dict = string(body[:s])
+			streamStart := start + s + len("stream")
+			if streamStart < len(data) && data[streamStart] == '\r' {
+				streamStart++
+			}
+			if streamStart < len(data) && data[streamStart] == '\n' {
+				streamStart++
+			}
+			// Use the length if it is direct, the data may contain "endobj"
+			streamEnd := -1
+			if length, err := strconv.Atoi(dictValue(obj.dict, "Length")); err == nil && length >= 0 && length <= len(data)-streamStart {
+				streamEnd = streamStart + length
+			} else if e := bytes.Index(data[streamStart:], []byte("endstream")); e >= 0 {
+				streamEnd = streamStart + e
+			}
+			if streamEnd >= 0 {
+				obj.stream = decodeStream(obj.dict, data[streamStart:streamEnd])
+				if e := bytes.Index(data[streamEnd:], []byte("endobj")); e >= 0 {
+					end = streamEnd + e - start
+				}
+			}
+		}
+		doc.objects[num] = obj
+		if strings.Contains(obj.dict, "/ObjStm") {
+			objStreams = append(objStreams, obj)
+		}
+		pos = start + end + len("endobj")
+	}
+
+	// Compressed object streams hold the dictionaries of newer PDFs
+	for _, stm := range objStreams {
+		n, _ := strconv.Atoi(dictValue(stm.dict, "N"))
+		first, _ := strconv.Atoi(dictValue(stm.dict, "First"))
+		if first < 0 || first > len(stm.stream) {
+			continue // Malformed, the objects can't be located
+		}
+		header := strings.Fields(string(stm.stream[:first]))
+		for i := 0; i+1 < len(header) && i/2 < n; i += 2 {
+			num, _ := strconv.Atoi(header[i])
+			off, err := strconv.Atoi(header[i+1])
+			if err != nil || off < 0 {
+				continue
+			}
+			end := len(stm.stream)
+			if i+3 < len(header) {
+				if next, err := strconv.Atoi(header[i+3]); err == nil {
+					end = first + next
+				}
+			}
+			if first+off <= end && end <= len(stm.stream) {
+				if _, ok := doc.objects[num]; !ok {
+					doc.objects[num] = pdfObject{dict: string(stm.stream[first+off : end])}
+				}
+			}
+		}
+	}
+
+	if roots := reRoot.FindAllSubmatch(data, -1); roots != nil {
+		doc.root, _ = strconv.Atoi(string(roots[len(roots)-1][1]))
+	}
+	return doc
+}
+
+func decodeStream(dict string, raw []byte) []byte {
+	if !strings.Contains(dict, "/FlateDecode") {
+		if strings.Contains(dict, "/Filter") {
+			return nil // Images and other encodings carry no text
+		}
+		return raw
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil
+	}
+	out, _ := io.ReadAll(zr) // Keep what was decoded of truncated streams
+	return out
+}
+
+// dictValue returns the raw value of /key in a dictionary: a nested
+// dictionary or array with its brackets, a reference "5 0 R" or a token.
+func dictValue(dict, key string) string {
+	for i := 0; ; {
+		j := strings.Index(dict[i:], "/"+key)
+		if j < 0 {
+			return ""
+		}
+		i += j + len(key) + 1
+		if i < len(dict) && !isDelimiter(dict[i]) {
+			continue // "/Font" matched "/FontFile"
+		}
+		rest := strings.TrimLeft(dict[i:], " \t\r\n")
+		switch {
+		case strings.HasPrefix(rest, "<<"):
+			return balanced(rest, "<<", ">>")
+		case strings.HasPrefix(rest, "["):
+			return balanced(rest, "[", "]")
+		}
+		if m := reRef.FindString(rest); m != "" {
+			return m
+		}
+		if rest == "" {
+			return ""
+		}
+		end := 1 // A name value starts with '/'
+		for end < len(rest) && !isDelimiter(rest[end]) {
+			end++
+		}
+		return rest[:end]
+	}
+}
+
+func isDelimiter(c byte) bool {
+	return strings.IndexByte(" \t\r\n/<>[]()%", c) >= 0
+}
+
+func balanced(s, open, close string) string {
+	depth := 0
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], open):
+			depth++
+			i += len(open)
+		case strings.HasPrefix(s[i:], close):
+			depth--
+			i += len(close)
+			if depth == 0 {
+				return s[:i]
+			}
+		default:
+			i++
+		}
+	}
+	return s
+}
+
+// resolve follows a reference to the object's dictionary.
+func (d *pdfDoc) resolve(value string) string {
+	if m := reRef.FindStringSubmatch(value); m != nil {
+		num, _ := strconv.Atoi(m[1])
+		return d.objects[num].dict
+	}
+	return value
+}
+
+func refNum(value string) int {
+	if m := reRef.FindStringSubmatch(value); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	return 0
+}
+
+func refNums(array string) []int {
+	var nums []int
+	for _, m := range regexp.MustCompile(`(\d+)\s+\d+\s+R`).FindAllStringSubmatch(array, -1) {
+		n, _ := strconv.Atoi(m[1])
+		nums = append(nums, n)
+	}
+	return nums
+}
+
+// pages walks the page tree in reading order, passing inherited resources down.
+func (d *pdfDoc) pages() []pdfPage {
+	var pages []pdfPage
+	seen := make(map[int]bool)
+	var walk func(num int, resources string)
+	walk = func(num int, resources string) {
+		if seen[num] {
+			return
+		}
+		seen[num] = true
+		dict := d.objects[num].dict
+		if r := dictValue(dict, "Resources"); r != "" {
+			resources = d.resolve(r)
+		}
+		if kids := dictValue(dict, "Kids"); kids != "" {
+			for _, kid := range refNums(kids) {
+				walk(kid, resources)
+			}
+			return
+		}
+		pages = append(pages, pdfPage{dict: dict, resources: resources})
+	}
+	if d.root != 0 {
+		walk(refNum(dictValue(d.objects[d.root].dict, "Pages")), "")
+	}
+	return pages
+}
+
+func (d *pdfDoc) pageText(page pdfPage) string {
+	// Font resource names like /F1 to their ToUnicode maps
+	fonts := make(map[string]*toUnicode)
+	fontDict := d.resolve(dictValue(page.resources, "Font"))
+	for _, m := range regexp.MustCompile(`/([^\s/<>\[\]()]+)\s+(\d+)\s+\d+\s+R`).FindAllStringSubmatch(fontDict, -1) {
+		num, _ := strconv.Atoi(m[2])
+		fonts[m[1]] = d.fontCMap(num)
+	}
+
+	var content []byte
+	contents := dictValue(page.dict, "Contents")
+	if strings.HasPrefix(contents, "[") {
+		for _, num := range refNums(contents) {
+			content = append(content, d.objects[num].stream...)
+			content = append(content, '\n')
+		}
+	} else if num := refNum(contents); num != 0 {
+		content = d.objects[num].stream
+	}
+	return contentText(content, fonts)
+}
+
+func (d *pdfDoc) fontCMap(num int) *toUnicode {
+	if cm, ok := d.cmaps[num]; ok {
+		return cm
+	}
+	var cm *toUnicode
+	if ref := refNum(dictValue(d.objects[num].dict, "ToUnicode")); ref != 0 {
+		cm = parseToUnicode(d.objects[ref].stream)
+	}
+	d.cmaps[num] = cm
+	return cm
+}
+
+// toUnicode is a font's ToUnicode CMap, mapping character codes to text.
+type toUnicode struct {
+	width int // Bytes per code
+	codes map[int]string
+}
+
+var (
+	reHex     = regexp.MustCompile(`<([0-9A-Fa-f\s]*)>`)
+	reBfChar  = regexp.MustCompile(`(?s)beginbfchar(.*?)endbfchar`)
+	reBfRange = regexp.MustCompile(`(?s)beginbfrange(.*?)endbfrange`)
+	reRange   = regexp.MustCompile(`<([0-9A-Fa-f]+)>\s*<([0-9A-Fa-f]+)>\s*(<[0-9A-Fa-f]*>|\[[^\]]*\])`)
+	reSpace   = regexp.MustCompile(`(?s)begincodespacerange\s*<([0-9A-Fa-f]+)>`)
+)
+
+func parseToUnicode(data []byte) *toUnicode {
+	if data == nil {
+		return nil
+	}
+	s := string(data)
+	cm := &toUnicode{width: 2, codes: make(map[int]string)}
+	if m := reSpace.FindStringSubmatch(s); m != nil {
+		cm.width = max(1, len(m[1])/2)
+	}
+	for _, block := range reBfChar.FindAllStringSubmatch(s, -1) {
+		hexes := reHex.FindAllStringSubmatch(block[1], -1)
+		for i := 0; i+1 < len(hexes); i += 2 {
+			cm.codes[hexInt(hexes[i][1])] = utf16Hex(hexes[i+1][1])
+		}
+	}
+	for _, block := range reBfRange.FindAllStringSubmatch(s, -1) {
+		for _, m := range reRange.FindAllStringSubmatch(block[1], -1) {
+			lo, hi := hexInt(m[1]), hexInt(m[2])
+			if hi-lo > 0xffff {
+				continue
+			}
+			if strings.HasPrefix(m[3], "[") {
+				for i, h := range reHex.FindAllStringSubmatch(m[3], -1) {
+					cm.codes[lo+i] = utf16Hex(h[1])
+				}
+				continue
+			}
+			dst := []rune(utf16Hex(strings.Trim(m[3], "<>")))
+			if len(dst) == 0 {
+				continue
+			}
+			for code := lo; code <= hi; code++ {
+				r := append([]rune{}, dst...)
+				r[len(r)-1] += rune(code - lo)
+				cm.codes[code] = string(r)
+			}
+		}
+	}
+	return cm
+}
+
+func hexInt(s string) int {
+	n, _ := strconv.ParseInt(strings.Join(strings.Fields(s), ""), 16, 64)
+	return int(n)
+}
+
+func utf16Hex(s string) string {
+	b, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		return ""
+	}
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	return string(utf16.Decode(u))
+}
+
+func (cm *toUnicode) decode(s []byte) string {
+	if cm == nil {
+		// Simple fonts without a map are usually plain ASCII
+		var b strings.Builder
+		for _, c := range s {
+			if c >= 0x20 && c < 0x7f {
+				b.WriteByte(c)
+			}
+		}
+		return b.String()
+	}
+	var b strings.Builder
+	for i := 0; i+cm.width <= len(s); i += cm.width {
+		code := 0
+		for _, c := range s[i : i+cm.width] {
+			code = code<<8 | int(c)
+		}
+		b.WriteString(cm.codes[code])
+	}
+	return b.String()
+}
+
+// contentText runs the text operators of a content stream and starts a new
+// line whenever the text moves down.
+func contentText(content []byte, fonts map[string]*toUnicode) string {
+	var b strings.Builder
+	var font *toUnicode
+	var operands []interface{} // []byte strings, float64 numbers, string names, []interface{} arrays
+	var arrays [][]interface{}
+	lastY := 0.0
+
+	newline := func() {
+		if s := b.String(); s != "" && !strings.HasSuffix(s, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	push := func(v interface{}) {
+		if len(arrays) > 0 {
+			arrays[len(arrays)-1] = append(arrays[len(arrays)-1], v)
+		} else {
+			operands = append(operands, v)
+		}
+	}
+	number := func(i int) float64 {
+		if i < len(operands) {
+			if f, ok := operands[i].(float64); ok {
+				return f
+			}
+		}
+		return 0
+	}
+	show := func(v interface{}) {
+		switch t := v.(type) {
+		case []byte:
+			b.WriteString(font.decode(t))
+		case []interface{}:
+			for _, e := range t {
+				if s, ok := e.([]byte); ok {
+					b.WriteString(font.decode(s))
+				} else if f, ok := e.(float64); ok && f < -250 && (font == nil || font.width == 1) {
+					b.WriteString(" ")
+				}
+			}
+		}
+	}
+
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0:
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, n := literalString(content[i:])
+			push(s)
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] == '<', c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			end := bytes.IndexByte(content[i:], '>')
+			if end < 0 {
+				// Unterminated string, take the rest of the stream
+				end = len(content) - i
+			}
+			h := strings.Join(strings.Fields(string(content[i+1:i+end])), "")
+			if len(h)%2 == 1 {
+				h += "0"
+			}
+			s, _ := hex.DecodeString(h)
+			push(s)
+			i += end + 1
+		case c == '[':
+			arrays = append(arrays, nil)
+			i++
+		case c == ']':
+			if len(arrays) > 0 {
+				arr := arrays[len(arrays)-1]
+				arrays = arrays[:len(arrays)-1]
+				push(arr)
+			}
+			i++
+		case c == '/':
+			j := i + 1
+			for j < len(content) && !isDelimiter(content[j]) {
+				j++
+			}
+			push(string(content[i+1 : j]))
+			i = j
+		default:
+			j := i
+			for j < len(content) && !isDelimiter(content[j]) {
+				j++
+			}
+			if j == i {
+				j++ // Stray delimiter such as ')' or '{'
+			}
+			word := string(content[i:j])
+			i = j
+			if f, err := strconv.ParseFloat(word, 64); err == nil {
+				push(f)
+				continue
+			}
+
+			switch word {
+			case "Tf":
+				if len(operands) > 0 {
+					if name, ok := operands[0].(string); ok {
+						font = fonts[name]
+					}
+				}
+			case "Tj":
+				if len(operands) > 0 {
+					show(operands[len(operands)-1])
+				}
+			case "TJ":
+				if len(operands) > 0 {
+					show(operands[len(operands)-1])
+				}
+			case "'", "\"":
+				newline()
+				if len(operands) > 0 {
+					show(operands[len(operands)-1])
+				}
+			case "T*":
+				newline()
+			case "Td", "TD":
+				if number(1) != 0 {
+					newline()
+				}
+			case "Tm":
+				if y := number(5); y != lastY {
+					newline()
+					lastY = y
+				}
+			case "ID":
+				// Skip inline image data
+				if e := bytes.Index(content[i:], []byte("EI")); e >= 0 {
+					i += e + 2
+				} else {
+					i = len(content)
+				}
+			}
+			operands = operands[:0]
+		}
+	}
+	return b.String()
+}
+
+// literalString reads a "(...)" string with escapes and returns its bytes
+// and the number of bytes consumed.
+func literalString(s []byte) ([]byte, int) {
+	var out []byte
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '(':
+			if depth > 0 {
+				out = append(out, c)
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return out, i + 1
+			}
+			out = append(out, c)
+		case '\\':
+			i++
+			if i >= len(s) {
+				return out, i
+			}
+			switch e := s[i]; e {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r', '\n':
+				// Line continuation
+				if e == '\r' && i+1 < len(s) && s[i+1] == '\n' {
+					i++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					v, n := 0, 0
+					for n < 3 && i < len(s) && s[i] >= '0' && s[i] <= '7' {
+						v = v*8 + int(s[i]-'0')
+						i++
+						n++
+					}
+					i--
+					out = append(out, byte(v))
+				} else {
+					out = append(out, e)
+				}
+			}
+		default:
+			out = append(out, c)
+		}
+	}
+	return out, len(s)
+}
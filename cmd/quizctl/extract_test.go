@@ -0,0 +1,164 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLiteralString(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		n    int
+	}{
+		{"(abc) Tj", "abc", 5},
+		{"(a(b)c)", "a(b)c", 7},
+		{`(a\)b)`, "a)b", 6},
+		{`(x\ny\101)`, "x\ny" + "A", 10},
+		{"(a\\\nb)", "ab", 6},
+		{"(open", "open", 5},
+		{`(trailing\`, "trailing", 10},
+	}
+	for _, tt := range tests {
+		got, n := literalString([]byte(tt.in))
+		if string(got) != tt.want || n != tt.n {
+			t.Errorf("literalString(%q) = %q, %d; want %q, %d", tt.in, got, n, tt.want, tt.n)
+		}
+	}
+}
+
+func TestContentText(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"BT (Hello) Tj T* [(Wor) -300 (ld)] TJ ET", "Hello\nWor ld"},
+		{"<48656C6C6F> Tj", "Hello"},
+		// Unterminated hex strings at the end of the stream must not panic
+		{"(a) Tj <", "a"},
+		{"(a) Tj <4142", "a"},
+	}
+	for _, tt := range tests {
+		if got := contentText([]byte(tt.in), nil); got != tt.want {
+			t.Errorf("contentText(%q) = %q; want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// pdfFile assembles a PDF from numbered object bodies, without an xref table
+// since the parser scans for objects. An empty body skips its number.
+func pdfFile(objects ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	for i, body := range objects {
+		if body == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, body)
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return b.Bytes()
+}
+
+func pdfStream(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func deflate(data string) []byte {
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	zw.Write([]byte(data))
+	zw.Close()
+	return b.Bytes()
+}
+
+const pdfContent = "BT /F1 12 Tf 72 700 Td (1. Question one) Tj 0 -20 Td (A. yes B. no) Tj ET"
+
+func writeFile(t *testing.T, name string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPDFText(t *testing.T) {
+	page := "<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 5 0 R >> >> /Contents 4 0 R >>"
+	font := "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>"
+
+	// The catalog, page tree and page of newer PDFs sit in an object stream
+	dicts := []string{"<< /Type /Catalog /Pages 2 0 R >>", "<< /Type /Pages /Kids [3 0 R] /Count 1 >>", page}
+	var header, body string
+	for i, d := range dicts {
+		header += fmt.Sprintf("%d %d ", i+1, len(body))
+		body += d + " "
+	}
+	objStm := pdfStream(fmt.Sprintf("/Type /ObjStm /N 3 /First %d /Filter /FlateDecode", len(header)), deflate(header+body))
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"plain", pdfFile(dicts[0], dicts[1], page, pdfStream("", []byte(pdfContent)), font)},
+		{"compressed", pdfFile("", "", "", pdfStream("/Filter /FlateDecode", deflate(pdfContent)), font, objStm)},
+	}
+	for _, tt := range tests {
+		got, err := pdfText(writeFile(t, tt.name+".pdf", tt.data))
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if want := "1. Question one\nA. yes B. no\n"; got != want {
+			t.Errorf("%s: pdfText = %q; want %q", tt.name, got, want)
+		}
+	}
+}
+
+func TestPDFTextMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"negative first", pdfFile("<< /Type /Catalog /Pages 2 0 R >>", pdfStream("/Type /ObjStm /N 1 /First -5", []byte("2 0 << >>")))},
+		{"negative offset", pdfFile("<< /Type /Catalog /Pages 2 0 R >>", pdfStream("/Type /ObjStm /N 1 /First 5", []byte("2 -3 << >>")))},
+		{"negative length", pdfFile("<< /Type /Catalog >>", "<< /Length -3 >>\nstream\nabc\nendstream")},
+		{"huge length", pdfFile("<< /Type /Catalog >>", "<< /Length 9223372036854775807 >>\nstream\nabc\nendstream")},
+	}
+	for _, tt := range tests {
+		// Malformed files have no text, but must not panic
+		if _, err := pdfText(writeFile(t, "bad.pdf", tt.data)); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
+
+func TestDocxText(t *testing.T) {
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	w, _ := zw.Create("word/document.xml")
+	w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>1、我国的</w:t></w:r><w:r><w:t xml:space="preserve">首都是（ ）</w:t></w:r></w:p>
+<w:p><w:r><w:t>A、上海</w:t><w:tab/><w:t>B、北京</w:t></w:r></w:p>
+<w:p><w:r><w:t>答案：B</w:t><w:br/><w:t>解析：略</w:t></w:r></w:p>
+</w:body></w:document>`))
+	zw.Close()
+
+	got, err := docxText(writeFile(t, "q.docx", b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "1、我国的首都是（ ）\nA、上海\tB、北京\n答案：B\n解析：略\n"; got != want {
+		t.Errorf("docxText = %q; want %q", got, want)
+	}
+
+	if _, err := docxText(writeFile(t, "q.txt.docx", []byte("not a zip"))); err == nil {
+		t.Error("no error for a file that is not a docx")
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+func runFixTypes(args []string) error {
+	fs := newFlagSet("fix-types")
+	bf := addBankFlags(fs)
+	fs.Parse(args)
+
+	questions, err := loadBank(*bf.in)
+	if err != nil {
+		return err
+	}
+	count := 0
+	for i := range questions {
+		content := questions[i].Content
+		if strings.Contains(content, "(多选题)") {
+			if questions[i].Type != "多选题" {
+				questions[i].Type = "多选题"
+				count++
+			}
+		} else if strings.Contains(content, "(单选题)") {
+			if questions[i].Type != "单选题" {
+				questions[i].Type = "单选题"
+				count++
+			}
+		}
+	}
+	fmt.Printf("Fixed %d questions\n", count)
+	return bf.write(questions)
+}
+
+func runFixTF(args []string) error {
+	fs := newFlagSet("fix-tf")
+	bf := addBankFlags(fs)
+	fs.Parse(args)
+
+	questions, err := loadBank(*bf.in)
+	if err != nil {
+		return err
+	}
+	count := 0
+	for i := range questions {
+		// Empty options or an explicit "判断题" type/content
+		q := &questions[i]
+		if len(q.Options) != 0 && q.Type != "判断题" && !strings.Contains(q.Content, "(判断题)") {
+			continue
+		}
+
+		q.Type = "判断题"
+		if len(q.Options) == 0 {
+			q.Options = []string{"A、正确", "B、错误"}
+		}
+		ans := strings.TrimSpace(q.Answer)
+		if ans == "正确" || ans == "对" {
+			q.Answer = "A"
+		} else if ans == "错误" || ans == "错" {
+			q.Answer = "B"
+		}
+		count++
+	}
+	fmt.Printf("Fixed %d True/False questions\n", count)
+	return bf.write(questions)
+}
@@ -0,0 +1,59 @@
+// Command quizctl prepares and maintains question bank JSON files.
+//
+//	go run ./cmd/quizctl <command> [flags]
+//
+// Commands that rewrite a bank read -in (questions.json), write -out
+// (defaulting to -in) and print a diff instead with -dry-run.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+var commands = []command{
+	{"parse", "build a bank from txt, docx or pdf question and answer files", runParse},
+	{"fix-types", "set the type from (单选题)/(多选题) markers in the content", runFixTypes},
+	{"fix-tf", "normalize true/false questions to options A、正确/B、错误", runFixTF},
+	{"check", "list multiple-choice questions with a single-letter answer", runCheck},
+	{"ai-fix-answers", "ask the AI for the answers of some questions", runAIFixAnswers},
+	{"validate", "check that a bank can be imported by the app", runValidate},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: quizctl <command> [flags]\n\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-15s %s\n", c.name, c.usage)
+	}
+	fmt.Fprintln(os.Stderr, "\nRun quizctl <command> -h for the flags of a command.")
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	for _, c := range commands {
+		if c.name == os.Args[1] {
+			if err := c.run(os.Args[2:]); err != nil {
+				fmt.Fprintln(os.Stderr, "quizctl "+c.name+":", err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
+	usage()
+	os.Exit(2)
+}
+
+// newFlagSet exits on bad flags like the flag package does for main.
+func newFlagSet(name string) *flag.FlagSet {
+	return flag.NewFlagSet("quizctl "+name, flag.ExitOnError)
+}
@@ -0,0 +1,302 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"quiz-app/internal/quizbank"
+)
+
+type AnswerInfo struct {
+	Answer      string
+	Explanation string
+	TF          bool // Written as √/×, 对/错 and the like
+}
+
+func runParse(args []string) error {
+	fs := newFlagSet("parse")
+	questionsPath := fs.String("questions", "", "question file (.txt, .docx or text-based .pdf)")
+	answersPath := fs.String("answers", "", "answer file (.txt, .docx or .pdf), optional if answers are inline")
+	outPath := fs.String("out", "questions.json", "bank file to write")
+	dryRun := fs.Bool("dry-run", false, "print a diff against -out instead of writing")
+	fs.Parse(args)
+	if *questionsPath == "" {
+		return fmt.Errorf("-questions is required")
+	}
+
+	lines, err := readLines(*questionsPath)
+	if err != nil {
+		return fmt.Errorf("reading question file: %w", err)
+	}
+	questions := parseQuestions(lines)
+
+	var answers map[uint]AnswerInfo
+	if *answersPath != "" {
+		lines, err := readLines(*answersPath)
+		if err != nil {
+			return fmt.Errorf("reading answer file: %w", err)
+		}
+		answers = parseAnswers(lines)
+	}
+
+	// Merge, the answer file wins over answers found in the stems
+	mergedCount, inlineCount, missing := 0, 0, 0
+	for i := range questions {
+		q := &questions[i]
+		if ans, ok := answers[q.ID]; ok {
+			q.Answer = ans.Answer
+			if ans.TF {
+				q.Type = "判断题"
+			} else if strings.Contains(q.Answer, ",") && q.Type == "单选题" {
+				q.Type = "多选题"
+			}
+			if ans.Explanation != "" {
+				q.Explanation = ans.Explanation
+			}
+			mergedCount++
+		} else if q.Answer != "" {
+			inlineCount++
+		} else {
+			missing++
+		}
+		// Same options as fix-tf gives them, so the A/B keys mean something
+		if q.Type == "判断题" && len(q.Options) == 0 {
+			q.Options = []string{"A、正确", "B、错误"}
+		}
+	}
+
+	fmt.Printf("Parsed %d questions, merged %d answers, %d inline answers, %d without answer.\n",
+		len(questions), mergedCount, inlineCount, missing)
+	return writeBank(*outPath, questions, *dryRun)
+}
+
+var (
+	// Question start: "1、..." or "1. ..."
+	reQStart = regexp.MustCompile(`^(\d+)\s*([、.．])\s*(.*)`)
+	// Option label: "A、..." or "A. ...", possibly several on one line
+	reOptionLabel = regexp.MustCompile(`(?:^|\s)([A-H])\s*[、.．]`)
+	// Inline answer in a stem: "（B）", "(A,C)", "（√）"
+	reInlineAnswer = regexp.MustCompile(`[（(]\s*([A-H](?:\s*[,，、]?\s*[A-H])*|√|×|对|错|正确|错误)\s*[）)]`)
+	// Answer or explanation line following a question: "答案：B", "解析：..."
+	reAnswerLine      = regexp.MustCompile(`^(?:正确|参考)?答案\s*[:：]\s*(.+)`)
+	reExplanationLine = regexp.MustCompile(`^(?:答案)?解析\s*[:：]\s*(.*)`)
+)
+
+func parseQuestions(lines []string) []quizbank.Question {
+	var questions []quizbank.Question
+	var currentQ *quizbank.Question
+
+	for _, line := range lines {
+		// Check for Question Start, "1.5" is a number not a question
+		if matches := reQStart.FindStringSubmatch(line); matches != nil && (matches[2] == "、" || !startsWithDigit(matches[3])) {
+			id, _ := strconv.Atoi(matches[1])
+			content := matches[3]
+
+			qType := "单选题" // Default
+			if strings.Contains(line, "(多选题)") {
+				qType = "多选题"
+			} else if strings.Contains(line, "(判断题)") {
+				qType = "判断题"
+			} else if strings.Contains(line, "(单选题)") {
+				qType = "单选题"
+			}
+
+			q := quizbank.Question{
+				ID:      uint(id),
+				Type:    qType,
+				Content: content,
+				Options: []string{},
+			}
+			questions = append(questions, q)
+			currentQ = &questions[len(questions)-1]
+			continue
+		}
+		if currentQ == nil {
+			continue
+		}
+
+		if matches := reAnswerLine.FindStringSubmatch(line); matches != nil {
+			answer, tf := answerKey(matches[1])
+			currentQ.Answer = answer
+			if tf {
+				currentQ.Type = "判断题"
+			}
+			continue
+		}
+		if matches := reExplanationLine.FindStringSubmatch(line); matches != nil {
+			currentQ.Explanation = matches[1]
+			continue
+		}
+
+		// Check for Options
+		if options := splitOptions(line); options != nil {
+			currentQ.Options = append(currentQ.Options, options...)
+			continue
+		}
+
+		// Append to content if it's a continuation of the question and not an option
+		if len(currentQ.Options) == 0 {
+			currentQ.Content += " " + line
+		}
+	}
+
+	for i := range questions {
+		extractInlineAnswer(&questions[i])
+	}
+	return questions
+}
+
+func startsWithDigit(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
+
+// splitOptions splits a line of options like "A、甲  B、乙  C、丙" into
+// one option each. It returns nil if the line does not start with an option.
+func splitOptions(line string) []string {
+	locs := reOptionLabel.FindAllStringSubmatchIndex(line, -1)
+	if len(locs) == 0 || locs[0][0] != 0 {
+		return nil
+	}
+	// Labels must run on from the first one, or "B、" in a sentence would split it
+	first := line[locs[0][2]]
+	var starts []int
+	for _, loc := range locs {
+		if line[loc[2]] == first+byte(len(starts)) {
+			starts = append(starts, loc[2])
+		}
+	}
+	var options []string
+	for i, start := range starts {
+		end := len(line)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		options = append(options, strings.TrimSpace(line[start:end]))
+	}
+	return options
+}
+
+// extractInlineAnswer takes an answer written into the stem, like
+// "我国的首都是（B）。", and leaves a blank in its place.
+func extractInlineAnswer(q *quizbank.Question) {
+	loc := reInlineAnswer.FindStringSubmatchIndex(q.Content)
+	if loc == nil {
+		return
+	}
+	answer, tf := answerKey(q.Content[loc[2]:loc[3]])
+	// A single letter in brackets may be part of the stem if there are no options
+	if len(q.Options) == 0 && !tf {
+		return
+	}
+	if q.Answer == "" {
+		q.Answer = answer
+	}
+	q.Content = q.Content[:loc[0]] + "（ ）" + q.Content[loc[1]:]
+
+	if tf {
+		q.Type = "判断题"
+	} else if strings.Contains(answer, ",") && q.Type == "单选题" && !strings.Contains(q.Content, "(单选题)") {
+		q.Type = "多选题"
+	}
+}
+
+// answerKey normalises an answer whose question type may not be known yet.
+// "A C", "a、c" and "ac" become "A,C"; a true/false word like √ or 错 becomes
+// the 判断题 letter A or B and reports tf. "F" stays an option letter.
+func answerKey(s string) (key string, tf bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if _, ok := quizbank.TFAliases[s]; ok && strings.Trim(s, "ABCDEFGH") != "" {
+		return quizbank.NormalizeAnswer("判断题", s), true
+	}
+	return quizbank.NormalizeAnswer("多选题", s), false
+}
+
+// Compact answer sheet: "1-5 ABCDB", "6—10：A B C D A", "11-13 AB,ACD,BD"
+var reAnswerRange = regexp.MustCompile(`^\s*(\d+)\s*[-–—~～]\s*(\d+)\s*[:：.、]?\s*([A-Ha-h√×对错](?:[A-Ha-h√×对错\s,，、]*[A-Ha-h√×对错])?)`)
+
+// Regex to match: "1. B（脱贫攻坚精神）" or "61. 正确" or "20. D（...）- ..."
+// Group 1: ID
+// Group 2: Answer (A-Z, or A,B,C or 正确/错误)
+// Group 3: Explanation (content inside first parenthesis)
+var reAnswer = regexp.MustCompile(`^(\d+)\s*[.．、]\s*([A-Z,]+|正确|错误|对|错|√|×)(?:[（(](.*?)[）)])?`)
+
+func parseAnswers(lines []string) map[uint]AnswerInfo {
+	answers := make(map[uint]AnswerInfo)
+	for _, line := range lines {
+		if parseAnswerRanges(line, answers) {
+			continue
+		}
+
+		if matches := reAnswer.FindStringSubmatch(line); matches != nil {
+			id, _ := strconv.Atoi(matches[1])
+			ans, tf := answerKey(matches[2])
+			expl := ""
+			if len(matches) > 3 {
+				expl = matches[3]
+			}
+
+			answers[uint(id)] = AnswerInfo{
+				Answer:      ans,
+				Explanation: expl,
+				TF:          tf,
+			}
+		}
+	}
+	return answers
+}
+
+// parseAnswerRanges reads the compact ranges at the start of a line, several
+// may follow each other ("1-5 ABCDB 6-10 ACDBA"). It reports whether any was
+// found, so other lines fall through to the one-answer-per-line format.
+func parseAnswerRanges(line string, answers map[uint]AnswerInfo) bool {
+	found := false
+	rest := line
+	for {
+		m := reAnswerRange.FindStringSubmatch(rest)
+		if m == nil {
+			return found
+		}
+		from, _ := strconv.Atoi(m[1])
+		to, _ := strconv.Atoi(m[2])
+		keys := splitAnswerRange(m[3], to-from+1)
+		if keys == nil {
+			fmt.Printf("Answer range %s-%s has %q, expected %d answers, skipped\n", m[1], m[2], m[3], to-from+1)
+			return found
+		}
+		for i, key := range keys {
+			answers[uint(from+i)] = key
+		}
+		found = true
+		rest = rest[len(m[0]):]
+	}
+}
+
+// splitAnswerRange splits the answers of a range of n questions. Single
+// letters may be run together ("ABCDB"); multiple-choice answers must be
+// separated ("AB,ACD,BD" or "AB ACD BD").
+func splitAnswerRange(s string, n int) []AnswerInfo {
+	if n <= 0 {
+		return nil
+	}
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ',' || r == '，' || r == '、'
+	})
+	if len(fields) == n {
+		keys := make([]AnswerInfo, n)
+		for i, f := range fields {
+			keys[i].Answer, keys[i].TF = answerKey(f)
+		}
+		return keys
+	}
+	runes := []rune(strings.Join(fields, ""))
+	if len(runes) != n {
+		return nil
+	}
+	keys := make([]AnswerInfo, n)
+	for i, r := range runes {
+		keys[i].Answer, keys[i].TF = answerKey(string(r))
+	}
+	return keys
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"quiz-app/internal/quizbank"
+)
+
+func TestSplitOptions(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"A、甲  B、乙  C、丙", []string{"A、甲", "B、乙", "C、丙"}},
+		{"A.1.5 倍 B.2 倍", []string{"A.1.5 倍", "B.2 倍"}},
+		{"C、丙 D、丁", []string{"C、丙", "D、丁"}},
+		{"A、坚持 C、原则", []string{"A、坚持 C、原则"}},
+		{"我国 A、甲", nil},
+	}
+	for _, tt := range tests {
+		if got := splitOptions(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitOptions(%q) = %q; want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestExtractInlineAnswer(t *testing.T) {
+	tests := []struct {
+		in   quizbank.Question
+		want quizbank.Question
+	}{
+		{
+			quizbank.Question{Type: "单选题", Content: "我国的首都是（B）。", Options: []string{"A、上海", "B、北京"}},
+			quizbank.Question{Type: "单选题", Content: "我国的首都是（ ）。", Options: []string{"A、上海", "B、北京"}, Answer: "B"},
+		},
+		{
+			quizbank.Question{Type: "单选题", Content: "下列说法正确的是（AC）", Options: []string{"A、甲", "B、乙", "C、丙"}},
+			quizbank.Question{Type: "多选题", Content: "下列说法正确的是（ ）", Options: []string{"A、甲", "B、乙", "C、丙"}, Answer: "A,C"},
+		},
+		{
+			quizbank.Question{Type: "单选题", Content: "人民是历史的创造者。（√）"},
+			quizbank.Question{Type: "判断题", Content: "人民是历史的创造者。（ ）", Answer: "A"},
+		},
+		// Without options a bracketed letter is part of the stem
+		{
+			quizbank.Question{Type: "单选题", Content: "维生素（C）的作用"},
+			quizbank.Question{Type: "单选题", Content: "维生素（C）的作用"},
+		},
+	}
+	for _, tt := range tests {
+		got := tt.in
+		extractInlineAnswer(&got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("extractInlineAnswer(%q) = %+v; want %+v", tt.in.Content, got, tt.want)
+		}
+	}
+}
+
+func TestSplitAnswerRange(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want []AnswerInfo
+	}{
+		{"ABCDB", 5, []AnswerInfo{{Answer: "A"}, {Answer: "B"}, {Answer: "C"}, {Answer: "D"}, {Answer: "B"}}},
+		{"A B C D A", 5, []AnswerInfo{{Answer: "A"}, {Answer: "B"}, {Answer: "C"}, {Answer: "D"}, {Answer: "A"}}},
+		{"AB,ACD,BD", 3, []AnswerInfo{{Answer: "A,B"}, {Answer: "A,C,D"}, {Answer: "B,D"}}},
+		{"√×√", 3, []AnswerInfo{{Answer: "A", TF: true}, {Answer: "B", TF: true}, {Answer: "A", TF: true}}},
+		{"ABCD", 5, nil},
+		{"AB", 0, nil},
+	}
+	for _, tt := range tests {
+		if got := splitAnswerRange(tt.s, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitAnswerRange(%q, %d) = %v; want %v", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestParseAnswers(t *testing.T) {
+	tests := []struct {
+		line string
+		want map[uint]AnswerInfo
+	}{
+		{"1. B（脱贫攻坚精神）", map[uint]AnswerInfo{1: {Answer: "B", Explanation: "脱贫攻坚精神"}}},
+		{"61. 对", map[uint]AnswerInfo{61: {Answer: "A", TF: true}}},
+		// A year range in the explanation is not an answer range
+		{"5. C（1978-1992 对外开放逐步扩大）", map[uint]AnswerInfo{5: {Answer: "C", Explanation: "1978-1992 对外开放逐步扩大"}}},
+		{"1-3 ABC 4—5：D A", map[uint]AnswerInfo{1: {Answer: "A"}, 2: {Answer: "B"}, 3: {Answer: "C"}, 4: {Answer: "D"}, 5: {Answer: "A"}}},
+		{"11-12 AB,ACD", map[uint]AnswerInfo{11: {Answer: "A,B"}, 12: {Answer: "A,C,D"}}},
+		{"62. ×", map[uint]AnswerInfo{62: {Answer: "B", TF: true}}},
+		{"第一部分", map[uint]AnswerInfo{}},
+	}
+	for _, tt := range tests {
+		if got := parseAnswers([]string{tt.line}); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseAnswers(%q) = %v; want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseQuestionsTrueFalse(t *testing.T) {
+	lines := []string{"1、人民是历史的创造者。", "答案：对", "2、下列说法正确的是", "A、甲", "B、乙", "答案：F"}
+	got := parseQuestions(lines)
+	if len(got) != 2 {
+		t.Fatalf("parseQuestions returned %d questions; want 2", len(got))
+	}
+	if got[0].Type != "判断题" || got[0].Answer != "A" {
+		t.Errorf("question 1 = %s %q; want 判断题 \"A\"", got[0].Type, got[0].Answer)
+	}
+	if got[1].Type != "单选题" || got[1].Answer != "F" {
+		t.Errorf("question 2 = %s %q; want 单选题 \"F\"", got[1].Type, got[1].Answer)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"gorm.io/gorm"
+)
+
+const (
+	dbFileName   = "quiz.db"
+	EventDBError = "db:error"
+)
+
+// AppConfig holds settings needed before the DB is open, stored next to ai.json.
+type AppConfig struct {
+	DBPath string `json:"db_path"`
+}
+
+// DBStatus tells the UI which database is in use and why.
+type DBStatus struct {
+	Path         string `json:"path"`
+	Source       string `json:"source"`                  // flag, env, settings, default or memory
+	MigratedFrom string `json:"migrated_from,omitempty"` // Old quiz.db copied on first run
+	Pending      string `json:"pending,omitempty"`       // Path saved for the next start
+	Error        string `json:"error,omitempty"`
+}
+
+func appConfigDir() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir, _ = os.Getwd()
+	}
+	return filepath.Join(dir, "quiz-app")
+}
+
+func appConfigPath() string {
+	return filepath.Join(appConfigDir(), "config.json")
+}
+
+func loadAppConfig() AppConfig {
+	var c AppConfig
+	if data, err := os.ReadFile(appConfigPath()); err == nil {
+		json.Unmarshal(data, &c)
+	}
+	return c
+}
+
+func saveAppConfig(c AppConfig) error {
+	if err := os.MkdirAll(appConfigDir(), 0o700); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(c, "", "  ")
+	return os.WriteFile(appConfigPath(), data, 0o600)
+}
+
+// resolveDBPath picks the database file: the -db flag, then QUIZ_DB_PATH,
+// then the saved setting, then the per-user config directory.
+func resolveDBPath(flagPath string) (string, string) {
+	if flagPath != "" {
+		return flagPath, "flag"
+	}
+	if v := os.Getenv("QUIZ_DB_PATH"); v != "" {
+		return v, "env"
+	}
+	if c := loadAppConfig(); c.DBPath != "" {
+		return c.DBPath, "settings"
+	}
+	return filepath.Join(appConfigDir(), dbFileName), "default"
+}
+
+// migrateCwdDB copies a quiz.db from the working directory, where older
+// versions kept it, to path if nothing is there yet. The old file is left in place.
+func migrateCwdDB(path string) (string, error) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", nil
+	}
+	old := filepath.Join(cwd, dbFileName)
+	if abs, err := filepath.Abs(path); err == nil && abs == old {
+		return "", nil
+	}
+	if _, err := os.Stat(path); err == nil {
+		return "", nil
+	}
+	if _, err := os.Stat(old); err != nil {
+		return "", nil
+	}
+	if err := copyFile(old, path); err != nil {
+		return "", fmt.Errorf("迁移旧数据库失败: %w", err)
+	}
+	return old, nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	tmp := dst + ".tmp"
+	out, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// migrateSchema brings the tables up to date and returns the tables moved
+// aside for the profile migration.
+func migrateSchema(db *gorm.DB) ([]string, error) {
+	moved := prepareProfileMigration(db)
+	err := db.AutoMigrate(
+		&Profile{}, &Bank{}, &Question{}, &UserProgress{}, &MistakeBook{},
+		&Attempt{}, &ReviewCard{}, &ExamSession{}, &ExamItem{}, &Setting{},
+		&AIJob{}, &AIJobItem{}, &AIExplanationRecord{}, &ChatMessage{}, &BankVersion{}, &KeyChange{},
+		&Tag{}, &QuestionTag{}, &TopicSuggestion{}, &Note{},
+	)
+	return moved, err
+}
+
+// openDB opens the database file, creating its directory, and checks that it is writable.
+func (a *App) openDB() ([]string, error) {
+	path, source := resolveDBPath(a.dbFlag)
+	a.dbStatus = DBStatus{Path: path, Source: source}
+
+	if source == "default" {
+		from, err := migrateCwdDB(path)
+		if err != nil {
+			return nil, err
+		}
+		a.dbStatus.MigratedFrom = from
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("无法创建数据目录: %w", err)
+	}
+
+	// Background jobs write concurrently, wait for locks instead of failing
+	db, err := gorm.Open(sqlite.Open(path+"?_pragma=busy_timeout(5000)"), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("无法打开数据库: %w", err)
+	}
+	moved, err := migrateSchema(db)
+	if err != nil {
+		closeDB(db)
+		return nil, fmt.Errorf("无法升级数据库: %w", err)
+	}
+	if err := db.Save(&Setting{Key: "last_opened", Value: time.Now().Format(time.RFC3339)}).Error; err != nil {
+		closeDB(db)
+		return nil, fmt.Errorf("数据库不可写: %w", err)
+	}
+	a.db = db
+	return moved, nil
+}
+
+func closeDB(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// openMemoryDB keeps the app usable for this session when the file cannot be opened.
+func (a *App) openMemoryDB() ([]string, error) {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	// Every connection would get its own empty in-memory database
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.SetMaxOpenConns(1)
+	}
+	moved, err := migrateSchema(db)
+	if err != nil {
+		closeDB(db)
+		return nil, err
+	}
+	a.db = db
+	a.dbStatus.Source = "memory"
+	return moved, nil
+}
+
+// warnDBStatus tells the user once the window is up that their data is not
+// being saved, or quits if there is no database at all.
+func (a *App) warnDBStatus() {
+	if a.db == nil {
+		runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+			Type:    runtime.ErrorDialog,
+			Title:   "无法打开数据库",
+			Message: a.dbStatus.Error,
+		})
+		runtime.Quit(a.ctx)
+		return
+	}
+	if a.dbStatus.Source != "memory" {
+		return
+	}
+	a.emit(EventDBError, a.dbStatus)
+	runtime.MessageDialog(a.ctx, runtime.MessageDialogOptions{
+		Type:  runtime.WarningDialog,
+		Title: "无法打开数据库",
+		Message: fmt.Sprintf("%s\n\n数据库：%s\n\n本次使用临时数据库，退出后所有做题记录都会丢失。请检查文件权限，或在设置中更换数据库位置后重启。",
+			a.dbStatus.Error, a.dbStatus.Path),
+	})
+}
+
+// GetDBStatus reports where the data is stored and any error opening it.
+func (a *App) GetDBStatus() DBStatus {
+	status := a.dbStatus
+	if c := loadAppConfig(); c.DBPath != "" && c.DBPath != status.Path {
+		status.Pending = c.DBPath
+	}
+	return status
+}
+
+// SetDBPath saves the database location used from the next start on.
+// An empty path goes back to the default location.
+func (a *App) SetDBPath(path string) error {
+	path = strings.TrimSpace(path)
+	if path != "" {
+		if !filepath.IsAbs(path) {
+			return errors.New("请填写绝对路径")
+		}
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			path = filepath.Join(path, dbFileName)
+		}
+	}
+	c := loadAppConfig()
+	c.DBPath = path
+	return saveAppConfig(c)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+
+	"quiz-app/internal/quizbank"
+)
+
+// Matches the "答案：X" line of a formatted or streamed AI explanation.
+// The letters stop at the end of the line, the analysis may follow on the next.
+var reAIAnswer = regexp.MustCompile(`答案[：:]\s*([A-Ha-h][A-Ha-h \t,，、]*|正确|错误|对|错)`)
+
+// parseAIAnswer extracts the AI's answer from explanation text.
+func parseAIAnswer(qType string, text string) string {
+	m := reAIAnswer.FindStringSubmatch(text)
+	if m == nil {
+		return ""
+	}
+	return quizbank.NormalizeAnswer(qType, m[1])
+}
+
+// isDisputed reports whether the AI's answer disagrees with the answer key.
+func isDisputed(q Question) bool {
+	if q.AIAnswer == "" {
+		return false
+	}
+	return quizbank.NormalizeAnswer(q.Type, q.AIAnswer) != quizbank.NormalizeAnswer(q.Type, q.Answer)
+}
+
+type DisputedQuestion struct {
+	ID            uint     `json:"id"`
+	Type          string   `json:"type"`
+	Content       string   `json:"content"`
+	Options       []string `json:"options"`
+	Answer        string   `json:"answer"`
+	AIAnswer      string   `json:"ai_answer"`
+	AIExplanation string   `json:"ai_explanation"`
+}
+
+// GetDisputedQuestions lists questions of the active bank where the AI
+// disagrees with the key, to audit suspicious answer keys.
+func (a *App) GetDisputedQuestions() []DisputedQuestion {
+	var questions []Question
+	a.db.Where("id IN (?)", a.bankQuestionIDs()).
+		Where("ai_answer <> '' AND dispute_dismissed = ?", false).
+		Order("id ASC").Find(&questions)
+
+	result := []DisputedQuestion{}
+	for _, q := range questions {
+		if !isDisputed(q) {
+			continue
+		}
+		var opts []string
+		json.Unmarshal([]byte(q.Options), &opts)
+		result = append(result, DisputedQuestion{
+			ID:            q.ID,
+			Type:          q.Type,
+			Content:       q.Content,
+			Options:       opts,
+			Answer:        q.Answer,
+			AIAnswer:      q.AIAnswer,
+			AIExplanation: q.AIExplanation,
+		})
+	}
+	return result
+}
+
+// DismissDispute hides a question from the disputed list after it was checked.
+// It shows up again only if a new explanation changes the AI's answer.
+func (a *App) DismissDispute(id uint) {
+	a.db.Model(&Question{}).Where("id = ?", id).Update("dispute_dismissed", true)
+}
@@ -0,0 +1,40 @@
+package main
+
+import "testing"
+
+func TestParseAIAnswer(t *testing.T) {
+	tests := []struct {
+		qType, text, want string
+	}{
+		{"单选题", "答案：A\nBecause the question asks…", "A"},
+		{"多选题", "答案: A, C、D\n解析：略", "A,C,D"},
+		{"多选题", "答案：\nBD", "B,D"},
+		{"判断题", "答案：正确\n解析", "A"},
+		{"单选题", "没有答案行", ""},
+	}
+	for _, tt := range tests {
+		if got := parseAIAnswer(tt.qType, tt.text); got != tt.want {
+			t.Errorf("parseAIAnswer(%q) = %q; want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestGetDisputedQuestionsActiveBank(t *testing.T) {
+	a := newTestApp(t)
+	bank, err := a.ImportBank(writeBankFile(t, testBank), "测试")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other Question
+	a.db.Where("bank_id = ?", bank.ID).Order("id ASC").First(&other)
+	a.db.Model(&other).Update("ai_answer", "B")
+
+	var own Question
+	a.db.Where("bank_id = ?", a.activeBank).Order("id ASC").First(&own)
+	a.db.Model(&own).Update("ai_answer", "H") // Never the key
+
+	got := a.GetDisputedQuestions()
+	if len(got) != 1 || got[0].ID != own.ID {
+		t.Errorf("disputes = %+v; want only question %d", got, own.ID)
+	}
+}
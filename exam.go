@@ -0,0 +1,340 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type ExamConfig struct {
+	SingleCount  int     `json:"single_count"`
+	MultiCount   int     `json:"multi_count"`
+	TFCount      int     `json:"tf_count"`
+	SinglePoints float64 `json:"single_points"`
+	MultiPoints  float64 `json:"multi_points"`
+	TFPoints     float64 `json:"tf_points"`
+	TimeLimit    int     `json:"time_limit"` // Minutes
+}
+
+// ExamSession is a mock exam paper. Exams are stored apart from the
+// practice UserProgress so they never affect the practice grid.
+type ExamSession struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	ProfileID   uint       `gorm:"index" json:"profile_id"`
+	Config      string     `json:"config"` // JSON of ExamConfig
+	Status      string     `json:"status"` // active, submitted
+	StartedAt   time.Time  `json:"started_at"`
+	EndsAt      time.Time  `json:"ends_at"`
+	SubmittedAt *time.Time `json:"submitted_at"`
+	Score       float64    `json:"score"`
+	TotalPoints float64    `json:"total_points"`
+}
+
+type ExamItem struct {
+	ID         uint    `gorm:"primaryKey" json:"id"`
+	ExamID     uint    `gorm:"index" json:"exam_id"`
+	QuestionID uint    `json:"question_id"`
+	Position   int     `json:"position"`
+	Type       string  `json:"type"`
+	Points     float64 `json:"points"`
+	Answer     string  `json:"answer"`
+	Correct    bool    `json:"correct"`
+	Score      float64 `json:"score"`
+}
+
+var examTypes = []string{"单选题", "多选题", "判断题"}
+
+func (c ExamConfig) count(qType string) int {
+	switch qType {
+	case "单选题":
+		return c.SingleCount
+	case "多选题":
+		return c.MultiCount
+	case "判断题":
+		return c.TFCount
+	}
+	return 0
+}
+
+func (c ExamConfig) points(qType string) float64 {
+	switch qType {
+	case "单选题":
+		return c.SinglePoints
+	case "多选题":
+		return c.MultiPoints
+	case "判断题":
+		return c.TFPoints
+	}
+	return 0
+}
+
+type ExamView struct {
+	ExamSession
+	Remaining int64      `json:"remaining"` // Seconds
+	Items     []ExamItem `json:"items"`
+}
+
+// StartExam draws a random paper by question type and makes it the active exam.
+func (a *App) StartExam(config ExamConfig) (ExamView, error) {
+	if config.SingleCount+config.MultiCount+config.TFCount <= 0 {
+		return ExamView{}, errors.New("试卷题目数量不能为 0")
+	}
+	if config.TimeLimit <= 0 {
+		return ExamView{}, errors.New("考试时长必须大于 0")
+	}
+	for _, t := range examTypes {
+		if config.count(t) > 0 && config.points(t) <= 0 {
+			return ExamView{}, fmt.Errorf("%s分值必须大于 0", t)
+		}
+	}
+	if a.activeExam != 0 {
+		var active ExamSession
+		if err := a.db.First(&active, a.activeExam).Error; err == nil {
+			a.expireExam(&active)
+			if active.Status == "active" {
+				return ExamView{}, errors.New("已有进行中的考试，请先交卷")
+			}
+		}
+		a.activeExam = 0
+	}
+
+	var items []ExamItem
+	var total float64
+	for _, t := range examTypes {
+		n := config.count(t)
+		if n <= 0 {
+			continue
+		}
+		var ids []uint
+		a.db.Model(&Question{}).Where("bank_id = ? AND type = ? AND retired = ?", a.activeBank, t, false).Order("RANDOM()").Limit(n).Pluck("id", &ids)
+		if len(ids) < n {
+			return ExamView{}, fmt.Errorf("%s数量不足：需要 %d 道，题库中只有 %d 道", t, n, len(ids))
+		}
+		for _, id := range ids {
+			items = append(items, ExamItem{
+				QuestionID: id,
+				Position:   len(items) + 1,
+				Type:       t,
+				Points:     config.points(t),
+			})
+			total += config.points(t)
+		}
+	}
+
+	cfg, _ := json.Marshal(config)
+	now := time.Now()
+	exam := ExamSession{
+		ProfileID:   a.activeProfile,
+		Config:      string(cfg),
+		Status:      "active",
+		StartedAt:   now,
+		EndsAt:      now.Add(time.Duration(config.TimeLimit) * time.Minute),
+		TotalPoints: total,
+	}
+	if err := a.db.Create(&exam).Error; err != nil {
+		return ExamView{}, err
+	}
+	for i := range items {
+		items[i].ExamID = exam.ID
+	}
+	if err := a.db.Create(&items).Error; err != nil {
+		return ExamView{}, err
+	}
+
+	a.activeExam = exam.ID
+	return a.GetExam(exam.ID)
+}
+
+// GetExam returns an exam with its items. Answers stay hidden while the exam is active.
+func (a *App) GetExam(id uint) (ExamView, error) {
+	var exam ExamSession
+	if err := a.mine().First(&exam, id).Error; err != nil {
+		return ExamView{}, errors.New("考试不存在")
+	}
+	if err := a.expireExam(&exam); err != nil {
+		return ExamView{}, err
+	}
+
+	var items []ExamItem
+	a.db.Where("exam_id = ?", id).Order("position ASC").Find(&items)
+
+	view := ExamView{ExamSession: exam, Items: items}
+	if exam.Status == "active" {
+		view.Remaining = int64(time.Until(exam.EndsAt).Seconds())
+		for i := range view.Items {
+			view.Items[i].Correct = false
+			view.Items[i].Score = 0
+		}
+	}
+	return view, nil
+}
+
+// GetActiveExam returns the exam currently in progress, or nil.
+func (a *App) GetActiveExam() *ExamView {
+	if a.activeExam == 0 {
+		return nil
+	}
+	view, err := a.GetExam(a.activeExam)
+	if err != nil || view.Status != "active" {
+		return nil
+	}
+	return &view
+}
+
+// SaveExamAnswer stores an answer on the paper without grading it.
+func (a *App) SaveExamAnswer(examID uint, questionID uint, answer string) error {
+	var exam ExamSession
+	if err := a.mine().First(&exam, examID).Error; err != nil {
+		return errors.New("考试不存在")
+	}
+	if err := a.expireExam(&exam); err != nil {
+		return err
+	}
+	if exam.Status != "active" {
+		return errors.New("考试已结束")
+	}
+
+	res := a.db.Model(&ExamItem{}).
+		Where("exam_id = ? AND question_id = ?", examID, questionID).
+		Update("answer", answer)
+	if res.RowsAffected == 0 {
+		return errors.New("该题不在试卷中")
+	}
+	return nil
+}
+
+// SubmitExam grades the paper and ends the exam.
+func (a *App) SubmitExam(examID uint) (ExamView, error) {
+	var exam ExamSession
+	if err := a.mine().First(&exam, examID).Error; err != nil {
+		return ExamView{}, errors.New("考试不存在")
+	}
+	if exam.Status == "active" {
+		if err := a.gradeExam(&exam); err != nil {
+			return ExamView{}, err
+		}
+	}
+	return a.GetExam(examID)
+}
+
+// resumeExam picks up an exam of the active profile that was running when
+// the app closed or the profile was switched away.
+func (a *App) resumeExam() {
+	a.activeExam = 0
+	var exam ExamSession
+	if err := a.mine().Where("status = ?", "active").Order("id DESC").First(&exam).Error; err == nil {
+		a.activeExam = exam.ID
+		a.expireExam(&exam)
+	}
+}
+
+// expireExam auto-submits an active exam once its time limit has passed.
+func (a *App) expireExam(exam *ExamSession) error {
+	if exam.Status == "active" && time.Now().After(exam.EndsAt) {
+		return a.gradeExam(exam)
+	}
+	return nil
+}
+
+func (a *App) gradeExam(exam *ExamSession) error {
+	var items []ExamItem
+	a.db.Where("exam_id = ?", exam.ID).Find(&items)
+
+	var score float64
+	for i := range items {
+		var q Question
+		a.db.First(&q, items[i].QuestionID)
+		grade := a.grader.Grade(q, items[i].Answer)
+		items[i].Correct = grade.Correct
+		items[i].Score = grade.Score * items[i].Points
+		score += items[i].Score
+		a.db.Save(&items[i])
+	}
+
+	now := time.Now()
+	exam.Status = "submitted"
+	exam.SubmittedAt = &now
+	exam.Score = score
+	if err := a.db.Save(exam).Error; err != nil {
+		return err
+	}
+
+	if a.activeExam == exam.ID {
+		a.activeExam = 0
+	}
+	return nil
+}
+
+// inActiveExam reports whether a question belongs to the running exam,
+// in which case its key and explanation must not be revealed.
+func (a *App) inActiveExam(questionID uint) (ExamItem, bool) {
+	if a.activeExam == 0 {
+		return ExamItem{}, false
+	}
+	var exam ExamSession
+	if err := a.db.First(&exam, a.activeExam).Error; err != nil {
+		a.activeExam = 0
+		return ExamItem{}, false
+	}
+	if a.expireExam(&exam) != nil || exam.Status != "active" {
+		return ExamItem{}, false
+	}
+
+	var item ExamItem
+	if err := a.db.Where("exam_id = ? AND question_id = ?", exam.ID, questionID).First(&item).Error; err != nil {
+		return ExamItem{}, false
+	}
+	return item, true
+}
+
+type TypeScore struct {
+	Type    string  `json:"type"`
+	Count   int     `json:"count"`
+	Correct int     `json:"correct"`
+	Score   float64 `json:"score"`
+	Points  float64 `json:"points"`
+}
+
+type ExamReport struct {
+	Exam   ExamView    `json:"exam"`
+	ByType []TypeScore `json:"by_type"`
+}
+
+// GetExamReport returns the scored breakdown of a submitted exam.
+func (a *App) GetExamReport(examID uint) (ExamReport, error) {
+	view, err := a.GetExam(examID)
+	if err != nil {
+		return ExamReport{}, err
+	}
+	if view.Status != "submitted" {
+		return ExamReport{}, errors.New("考试尚未交卷")
+	}
+
+	report := ExamReport{Exam: view}
+	for _, t := range examTypes {
+		ts := TypeScore{Type: t}
+		for _, item := range view.Items {
+			if item.Type != t {
+				continue
+			}
+			ts.Count++
+			ts.Points += item.Points
+			ts.Score += item.Score
+			if item.Correct {
+				ts.Correct++
+			}
+		}
+		if ts.Count > 0 {
+			report.ByType = append(report.ByType, ts)
+		}
+	}
+	return report, nil
+}
+
+// ListExams returns past and running exams, newest first.
+func (a *App) ListExams() []ExamSession {
+	exams := []ExamSession{}
+	a.mine().Order("id DESC").Find(&exams)
+	return exams
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStartExamRefusesSecondExam(t *testing.T) {
+	a := newTestApp(t)
+	config := ExamConfig{SingleCount: 2, SinglePoints: 2, TimeLimit: 30}
+
+	first, err := a.StartExam(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.StartExam(config); err == nil {
+		t.Fatal("second exam started while the first is active")
+	}
+
+	// Once the first one has run out of time a new exam may start
+	a.db.Model(&ExamSession{}).Where("id = ?", first.ID).Update("ends_at", time.Now().Add(-time.Minute))
+	if _, err := a.StartExam(config); err != nil {
+		t.Fatal(err)
+	}
+	var active int64
+	a.db.Model(&ExamSession{}).Where("status = ?", "active").Count(&active)
+	if active != 1 {
+		t.Errorf("%d active exams; want 1", active)
+	}
+}
+
+func TestStartExamNeedsPoints(t *testing.T) {
+	a := newTestApp(t)
+	if _, err := a.StartExam(ExamConfig{SingleCount: 2, TimeLimit: 30}); err == nil {
+		t.Error("exam started with 0 points per question")
+	}
+}
+
+func TestSubmitAnswerAfterTimeLimit(t *testing.T) {
+	a := newTestApp(t)
+	exam, err := a.StartExam(ExamConfig{SingleCount: 1, SinglePoints: 2, TimeLimit: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := exam.Items[0].QuestionID
+	if _, err := a.SubmitAnswer(id, "A"); err != nil {
+		t.Fatal(err)
+	}
+
+	a.db.Model(&ExamSession{}).Where("id = ?", exam.ID).Update("ends_at", time.Now().Add(-time.Minute))
+	if _, err := a.SubmitAnswer(id, "B"); err == nil {
+		t.Error("answer accepted after the time limit")
+	}
+	var item ExamItem
+	a.db.Where("exam_id = ? AND question_id = ?", exam.ID, id).First(&item)
+	if item.Answer != "A" {
+		t.Errorf("paper answer %q; want A", item.Answer)
+	}
+}
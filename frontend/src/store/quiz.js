@@ -1,6 +1,6 @@
 import { defineStore } from 'pinia'
 // These imports will work after 'wails dev' generates the bindings
-import { GetGrid, GetQuestion, SubmitAnswer, ToggleMark, SetMistakeMode, GetStats, RemoveFromMistakeBook, GenerateAIExplanation, GetCorrectMistakesCount, ClearCorrectMistakes } from '../../wailsjs/go/main/App'
+import { GetGrid, GetQuestion, SubmitAnswer, ToggleMark, SetMistakeMode, GetStats, RemoveFromMistakeBook, GenerateAIExplanation, GetCorrectMistakesCount, ClearCorrectMistakes, GetDBStatus } from '../../wailsjs/go/main/App'
 
 export const useQuizStore = defineStore('quiz', {
   state: () => ({
@@ -11,10 +11,16 @@ export const useQuizStore = defineStore('quiz', {
     showExplanation: false,
     lastResult: null, // { correct, explanation, correct_answer, ai_explanation }
     loading: false,
-    aiThinking: false
+    aiThinking: false,
+    dbStatus: null // { path, source, error }, source is "memory" if nothing is saved
   }),
   actions: {
     async init() {
+      try {
+        this.dbStatus = await GetDBStatus()
+      } catch (e) {
+        console.error(e)
+      }
       await this.fetchGrid()
       await this.fetchStats()
       // Load first question if grid is not empty and no current question
@@ -84,6 +90,7 @@ export const useQuizStore = defineStore('quiz', {
         }
       } catch (e) {
         console.error(e)
+        alert(e)
       }
     },
     async toggleMark() {
@@ -8,6 +8,8 @@ export function GenerateAIExplanation(arg1:number,arg2:boolean):Promise<string>;
 
 export function GetCorrectMistakesCount():Promise<number>;
 
+export function GetDBStatus():Promise<main.DBStatus>;
+
 export function GetGrid():Promise<Array<main.GridItem>>;
 
 export function GetQuestion(arg1:number):Promise<main.QuestionView>;
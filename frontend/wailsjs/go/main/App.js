@@ -14,6 +14,10 @@ export function GetCorrectMistakesCount() {
   return window['go']['main']['App']['GetCorrectMistakesCount']();
 }
 
+export function GetDBStatus() {
+  return window['go']['main']['App']['GetDBStatus']();
+}
+
 export function GetGrid() {
   return window['go']['main']['App']['GetGrid']();
 }
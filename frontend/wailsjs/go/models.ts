@@ -1,5 +1,25 @@
 export namespace main {
 	
+	export class DBStatus {
+	    path: string;
+	    source: string;
+	    migrated_from?: string;
+	    pending?: string;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new DBStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.source = source["source"];
+	        this.migrated_from = source["migrated_from"];
+	        this.pending = source["pending"];
+	        this.error = source["error"];
+	    }
+	}
 	export class GridItem {
 	    id: number;
 	    status: number;
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"strings"
+
+	"quiz-app/internal/quizbank"
+)
+
+// Partial-credit policies for 多选题
+const (
+	PolicyStrict       = "strict"       // All or nothing
+	PolicyHalf         = "half"         // Missing options but no wrong ones = half credit
+	PolicyProportional = "proportional" // Credit per picked correct option, nothing if any wrong
+)
+
+// Grade is the outcome of comparing an answer with the key.
+type Grade struct {
+	Correct bool     `json:"correct"`
+	Score   float64  `json:"score"` // 0..1
+	Answer  string   `json:"answer"`
+	Key     string   `json:"key"`
+	Missed  []string `json:"missed"`
+	Extra   []string `json:"extra"`
+}
+
+type Grader struct {
+	MultiPolicy string
+}
+
+func NewGrader() *Grader {
+	return &Grader{MultiPolicy: PolicyStrict}
+}
+
+func (g *Grader) Grade(q Question, answer string) Grade {
+	key := quizbank.AnswerLetters(q.Type, q.Answer)
+	given := quizbank.AnswerLetters(q.Type, answer)
+
+	inKey := make(map[string]bool, len(key))
+	for _, l := range key {
+		inKey[l] = true
+	}
+	inGiven := make(map[string]bool, len(given))
+	for _, l := range given {
+		inGiven[l] = true
+	}
+
+	grade := Grade{
+		Answer: strings.Join(given, ","),
+		Key:    strings.Join(key, ","),
+		Missed: []string{},
+		Extra:  []string{},
+	}
+	for _, l := range key {
+		if !inGiven[l] {
+			grade.Missed = append(grade.Missed, l)
+		}
+	}
+	for _, l := range given {
+		if !inKey[l] {
+			grade.Extra = append(grade.Extra, l)
+		}
+	}
+
+	grade.Correct = len(key) > 0 && len(grade.Missed) == 0 && len(grade.Extra) == 0
+	if grade.Correct {
+		grade.Score = 1
+		return grade
+	}
+
+	// Partial credit only applies to multi-choice with no wrong picks
+	if q.Type != "多选题" || len(given) == 0 || len(grade.Extra) > 0 {
+		return grade
+	}
+	switch g.MultiPolicy {
+	case PolicyHalf:
+		grade.Score = 0.5
+	case PolicyProportional:
+		grade.Score = float64(len(given)) / float64(len(key))
+	}
+	return grade
+}
+
+func validPolicy(policy string) bool {
+	switch policy {
+	case PolicyStrict, PolicyHalf, PolicyProportional:
+		return true
+	}
+	return false
+}
+
+func (a *App) GetGradingPolicy() string {
+	return a.grader.MultiPolicy
+}
+
+func (a *App) SetGradingPolicy(policy string) error {
+	if !validPolicy(policy) {
+		return errors.New("未知的评分策略: " + policy)
+	}
+	a.grader.MultiPolicy = policy
+	a.setSetting("grading_policy", policy)
+	return nil
+}
@@ -0,0 +1,60 @@
+// Package quizbank holds the question bank file format and the answer
+// normalisation shared by the app and quizctl.
+package quizbank
+
+import (
+	"sort"
+	"strings"
+)
+
+// Question is the question format of bank JSON files like questions.json.
+type Question struct {
+	ID            uint     `json:"id"`
+	Type          string   `json:"type"`
+	Content       string   `json:"content"`
+	Options       []string `json:"options"`
+	Answer        string   `json:"answer"`
+	Explanation   string   `json:"explanation"`
+	AIExplanation string   `json:"ai_explanation"`
+	Tags          []string `json:"tags,omitempty"` // Chapters or topics
+}
+
+// Types lists the question types a bank may use.
+var Types = []string{"单选题", "多选题", "判断题"}
+
+// TFAliases maps the ways a 判断题 answer is written to its option letter.
+var TFAliases = map[string]string{
+	"正确": "A", "对": "A", "√": "A", "✓": "A", "T": "A", "TRUE": "A",
+	"错误": "B", "错": "B", "×": "B", "✗": "B", "F": "B", "FALSE": "B",
+}
+
+// AnswerLetters extracts the option letters of an answer as a sorted, de-duplicated set.
+// "B,A", "AB", "A，B" and "a b" all become [A B].
+func AnswerLetters(qType string, answer string) []string {
+	s := strings.ToUpper(strings.TrimSpace(answer))
+	if qType == "判断题" {
+		if letter, ok := TFAliases[s]; ok {
+			return []string{letter}
+		}
+	}
+
+	seen := make(map[string]bool)
+	var letters []string
+	for _, r := range s {
+		if r < 'A' || r > 'H' {
+			continue
+		}
+		l := string(r)
+		if !seen[l] {
+			seen[l] = true
+			letters = append(letters, l)
+		}
+	}
+	sort.Strings(letters)
+	return letters
+}
+
+// NormalizeAnswer returns the canonical "A,B,C" form of an answer.
+func NormalizeAnswer(qType string, answer string) string {
+	return strings.Join(AnswerLetters(qType, answer), ",")
+}
@@ -0,0 +1,23 @@
+package quizbank
+
+import "testing"
+
+func TestNormalizeAnswer(t *testing.T) {
+	tests := []struct {
+		qType, answer, want string
+	}{
+		{"多选题", "B,A", "A,B"},
+		{"多选题", "a c c", "A,C"},
+		{"多选题", "A，B、D", "A,B,D"},
+		{"判断题", "对", "A"},
+		{"判断题", "✓", "A"},
+		{"判断题", "✗", "B"},
+		{"判断题", "false", "B"},
+		{"单选题", "正确", ""},
+	}
+	for _, tt := range tests {
+		if got := NormalizeAnswer(tt.qType, tt.answer); got != tt.want {
+			t.Errorf("NormalizeAnswer(%q, %q) = %q; want %q", tt.qType, tt.answer, got, tt.want)
+		}
+	}
+}
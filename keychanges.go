@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// KeyChange records that an answer key changed after the learner had answered
+// the question, and how their stored answer was re-graded.
+type KeyChange struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	ProfileID    uint      `gorm:"index" json:"profile_id"`
+	QuestionID   uint      `gorm:"index" json:"question_id"`
+	OldAnswer    string    `json:"old_answer"`
+	NewAnswer    string    `json:"new_answer"`
+	UserAnswer   string    `json:"user_answer"`
+	OldStatus    int       `json:"old_status"`
+	NewStatus    int       `json:"new_status"`
+	Acknowledged bool      `json:"acknowledged"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// onKeyChanged re-grades every profile's latest answer against the new key and
+// moves the question in or out of their mistake books accordingly.
+func (a *App) onKeyChanged(tx *gorm.DB, q Question, oldAnswer string) error {
+	var progress []UserProgress
+	// Never answered, nothing to re-grade
+	tx.Where("question_id = ? AND user_answer <> ''", q.ID).Find(&progress)
+
+	for _, p := range progress {
+		oldStatus := p.Status
+		p.Status = 2
+		if a.grader.Grade(q, p.UserAnswer).Correct {
+			p.Status = 1
+		}
+		p.KeyChanged = true
+		if err := tx.Save(&p).Error; err != nil {
+			return err
+		}
+
+		mb := MistakeBook{ProfileID: p.ProfileID, QuestionID: q.ID}
+		inBook := tx.Where("profile_id = ? AND question_id = ?", p.ProfileID, q.ID).First(&mb).Error == nil
+		switch {
+		case oldStatus == 2 && p.Status == 1 && inBook:
+			// The earlier mistake was caused by the wrong key
+			mb.Count--
+			if mb.Count <= 0 {
+				if err := tx.Delete(&mb).Error; err != nil {
+					return err
+				}
+			} else if err := tx.Save(&mb).Error; err != nil {
+				return err
+			}
+		case p.Status == 2 && oldStatus != 2:
+			mb.Count++
+			if err := tx.Save(&mb).Error; err != nil {
+				return err
+			}
+		}
+
+		err := tx.Create(&KeyChange{
+			ProfileID:  p.ProfileID,
+			QuestionID: q.ID,
+			OldAnswer:  oldAnswer,
+			NewAnswer:  q.Answer,
+			UserAnswer: p.UserAnswer,
+			OldStatus:  oldStatus,
+			NewStatus:  p.Status,
+		}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type KeyChangeView struct {
+	KeyChange
+	Number  uint     `json:"number"`
+	Type    string   `json:"type"`
+	Content string   `json:"content"`
+	Options []string `json:"options"`
+}
+
+// GetKeyChanges lists questions in the active bank whose answer key changed
+// since the active profile answered them and that have not been acknowledged yet.
+func (a *App) GetKeyChanges() []KeyChangeView {
+	var changes []KeyChange
+	a.mine().Where("acknowledged = ? AND question_id IN (?)", false, a.bankQuestionIDs()).Order("id DESC").Find(&changes)
+
+	views := make([]KeyChangeView, 0, len(changes))
+	for _, c := range changes {
+		var q Question
+		a.db.First(&q, c.QuestionID)
+		var opts []string
+		json.Unmarshal([]byte(q.Options), &opts)
+		views = append(views, KeyChangeView{
+			KeyChange: c,
+			Number:    q.Number,
+			Type:      q.Type,
+			Content:   q.Content,
+			Options:   opts,
+		})
+	}
+	return views
+}
+
+func (a *App) AcknowledgeKeyChange(id uint) {
+	var c KeyChange
+	if err := a.mine().First(&c, id).Error; err != nil {
+		return
+	}
+	a.db.Model(&c).Update("acknowledged", true)
+	a.mine().Model(&UserProgress{}).Where("question_id = ?", c.QuestionID).Update("key_changed", false)
+}
+
+func (a *App) AcknowledgeAllKeyChanges() {
+	ids := a.mine().Model(&KeyChange{}).Select("question_id").Where("acknowledged = ? AND question_id IN (?)", false, a.bankQuestionIDs())
+	a.mine().Model(&UserProgress{}).Where("question_id IN (?)", ids).Update("key_changed", false)
+	a.mine().Model(&KeyChange{}).Where("acknowledged = ? AND question_id IN (?)", false, a.bankQuestionIDs()).Update("acknowledged", true)
+}
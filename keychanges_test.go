@@ -0,0 +1,119 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestKeyChangeRegrades(t *testing.T) {
+	tests := []struct {
+		name     string
+		answer   string // Latest answer against the old key A, "" for never answered
+		mistakes int    // Mistake book count before the change, 0 if not in it
+		status   int    // Status against the new key B
+		want     int    // Mistake book count after the change
+	}{
+		{"wrong only because of the key", "B", 1, 1, 0},
+		{"earlier mistakes stay", "B", 2, 1, 1},
+		{"now wrong", "A", 0, 2, 1},
+		{"now wrong again", "A", 1, 2, 2},
+		{"wrong either way", "C", 1, 2, 1},
+		{"never answered", "", 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := newTestApp(t)
+			bank, err := a.ImportBank(writeBankFile(t, testBank), "测试")
+			if err != nil {
+				t.Fatal(err)
+			}
+			var q Question
+			a.db.Where("bank_id = ? AND number = ?", bank.ID, 1).First(&q)
+
+			oldStatus := 0
+			if tt.answer != "" {
+				oldStatus = 2
+				if tt.answer == "A" {
+					oldStatus = 1
+				}
+			}
+			a.db.Save(&UserProgress{ProfileID: a.activeProfile, QuestionID: q.ID, Status: oldStatus, UserAnswer: tt.answer})
+			if tt.mistakes > 0 {
+				a.db.Create(&MistakeBook{ProfileID: a.activeProfile, QuestionID: q.ID, Count: tt.mistakes})
+			}
+
+			newKey := strings.Replace(testBank, `"answer": "A"`, `"answer": "B"`, 1)
+			summary, err := a.syncBank(bank.ID, []byte(newKey))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if summary.KeyChange != 1 {
+				t.Errorf("summary counts %d key changes; want 1", summary.KeyChange)
+			}
+
+			var p UserProgress
+			a.mine().First(&p, "question_id = ?", q.ID)
+			if p.Status != tt.status || p.KeyChanged != (tt.answer != "") {
+				t.Errorf("progress = status %d, key changed %v; want %d, %v", p.Status, p.KeyChanged, tt.status, tt.answer != "")
+			}
+			var mb MistakeBook
+			a.mine().Where("question_id = ?", q.ID).Find(&mb)
+			if mb.Count != tt.want {
+				t.Errorf("mistake count = %d; want %d", mb.Count, tt.want)
+			}
+
+			var changes []KeyChange
+			a.mine().Where("question_id = ?", q.ID).Find(&changes)
+			if tt.answer == "" {
+				if len(changes) != 0 {
+					t.Errorf("%d key changes recorded for an unanswered question", len(changes))
+				}
+				return
+			}
+			if len(changes) != 1 {
+				t.Fatalf("%d key changes recorded; want 1", len(changes))
+			}
+			c := changes[0]
+			if c.OldAnswer != "A" || c.NewAnswer != "B" || c.UserAnswer != tt.answer || c.OldStatus != oldStatus || c.NewStatus != tt.status {
+				t.Errorf("key change = %+v", c)
+			}
+		})
+	}
+}
+
+// TestKeyChangeEveryProfile checks that other profiles are re-graded too.
+func TestKeyChangeEveryProfile(t *testing.T) {
+	a := newTestApp(t)
+	bank, err := a.ImportBank(writeBankFile(t, testBank), "测试")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var q Question
+	a.db.Where("bank_id = ? AND number = ?", bank.ID, 1).First(&q)
+	profile, err := a.CreateProfile("小明")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.db.Save(&UserProgress{ProfileID: profile.ID, QuestionID: q.ID, Status: 2, UserAnswer: "B"})
+	a.db.Create(&MistakeBook{ProfileID: profile.ID, QuestionID: q.ID, Count: 1})
+
+	newKey := strings.Replace(testBank, `"answer": "A"`, `"answer": "B"`, 1)
+	if _, err := a.syncBank(bank.ID, []byte(newKey)); err != nil {
+		t.Fatal(err)
+	}
+
+	var p UserProgress
+	a.db.First(&p, "profile_id = ? AND question_id = ?", profile.ID, q.ID)
+	if p.Status != 1 || !p.KeyChanged {
+		t.Errorf("other profile = status %d, key changed %v; want 1, true", p.Status, p.KeyChanged)
+	}
+	var count int64
+	a.db.Model(&MistakeBook{}).Where("profile_id = ? AND question_id = ?", profile.ID, q.ID).Count(&count)
+	if count != 0 {
+		t.Error("other profile's mistake was kept")
+	}
+	a.db.Model(&KeyChange{}).Where("profile_id = ?", a.activeProfile).Count(&count)
+	if count != 0 {
+		t.Errorf("%d key changes for the profile that never answered", count)
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"embed"
+	"flag"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -12,8 +13,20 @@ import (
 var assets embed.FS
 
 func main() {
+	dbPath := flag.String("db", "", "数据库文件路径，默认保存在用户配置目录")
+	flag.Parse()
+
 	// Create an instance of the app structure
 	app := NewApp()
+	app.dbFlag = *dbPath
+
+	// Without a database every bound method would fail, so nothing is bound
+	// and domReady only reports the error and quits
+	bind := []interface{}{app}
+	if err := app.initDB(); err != nil {
+		println("Error:", err.Error())
+		bind = nil
+	}
 
 	// Create application with options
 	err := wails.Run(&options.App{
@@ -25,9 +38,8 @@ func main() {
 		},
 		BackgroundColour: &options.RGBA{R: 255, G: 255, B: 255, A: 1},
 		OnStartup:        app.startup,
-		Bind: []interface{}{
-			app,
-		},
+		OnDomReady:       app.domReady,
+		Bind:             bind,
 	})
 
 	if err != nil {
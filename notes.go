@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"time"
+)
+
+// Note is the user's own Markdown note on a question, e.g. a mnemonic.
+type Note struct {
+	ProfileID  uint      `gorm:"primaryKey" json:"profile_id"`
+	QuestionID uint      `gorm:"primaryKey" json:"question_id"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// SaveNote stores the note of a question. An empty body deletes the note.
+func (a *App) SaveNote(id uint, body string) (Note, error) {
+	var q Question
+	if err := a.db.First(&q, id).Error; err != nil {
+		return Note{}, err
+	}
+
+	var note Note
+	if strings.TrimSpace(body) == "" {
+		err := a.mine().Where("question_id = ?", id).Delete(&Note{}).Error
+		a.reindexQuestion(q)
+		return note, err
+	}
+
+	if err := a.mine().First(&note, "question_id = ?", id).Error; err != nil {
+		note = Note{ProfileID: a.activeProfile, QuestionID: id}
+	}
+	note.Body = body
+	if err := a.db.Save(&note).Error; err != nil {
+		return Note{}, err
+	}
+	a.reindexQuestion(q)
+	return note, nil
+}
+
+// GetNote returns the note of a question, empty if there is none.
+func (a *App) GetNote(id uint) Note {
+	note := Note{ProfileID: a.activeProfile, QuestionID: id}
+	a.mine().First(&note, "question_id = ?", id)
+	return note
+}
+
+type NoteView struct {
+	Note
+	Number  uint   `json:"number"`
+	Content string `json:"content"`
+}
+
+// ListNotes returns the notes in the active bank, most recently edited first.
+func (a *App) ListNotes() []NoteView {
+	views := []NoteView{}
+	a.db.Table("notes").
+		Select("notes.*, questions.number, questions.content").
+		Joins("JOIN questions ON questions.id = notes.question_id").
+		Where("notes.profile_id = ? AND notes.question_id IN (?)", a.activeProfile, a.bankQuestionIDs()).
+		Order("notes.updated_at DESC").
+		Scan(&views)
+	return views
+}
+
+// SetNotesFilter limits the grid, stats and practice sessions to questions
+// with a note.
+func (a *App) SetNotesFilter(enable bool) {
+	a.notesOnly = enable
+	a.mistakeSession = make(map[uint]int)
+	a.reviewSession = make(map[uint]int)
+	if a.ReviewMode {
+		a.SetReviewMode(true, 0)
+	}
+}
+
+func (a *App) GetNotesFilter() bool {
+	return a.notesOnly
+}
@@ -0,0 +1,201 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// The profile learning data from before profiles existed belongs to
+const defaultProfileID = 1
+
+// Profile is a learner. Progress, mistakes, marks, notes, attempts, review
+// cards, exams and chats are kept per profile; banks and AI explanations are shared.
+type Profile struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// keyedProfileTables were keyed by question_id alone before profiles and
+// now use (profile_id, question_id), which AutoMigrate cannot change.
+var keyedProfileTables = []string{"user_progresses", "mistake_books", "review_cards", "notes"}
+
+// profileModels are all models that belong to a profile.
+var profileModels = []interface{}{
+	&UserProgress{}, &MistakeBook{}, &ReviewCard{}, &Note{},
+	&Attempt{}, &ExamSession{}, &KeyChange{}, &ChatMessage{},
+}
+
+// mine scopes a query on a per-profile table to the active profile.
+func (a *App) mine() *gorm.DB {
+	return a.db.Where("profile_id = ?", a.activeProfile)
+}
+
+// prepareProfileMigration moves tables still keyed by question_id aside so
+// AutoMigrate creates them with the new key. It returns the moved tables.
+func prepareProfileMigration(db *gorm.DB) []string {
+	var moved []string
+	for _, table := range keyedProfileTables {
+		if !db.Migrator().HasTable(table) || db.Migrator().HasColumn(table, "profile_id") {
+			continue
+		}
+		// Copy instead of renaming so the old indexes go away with the table
+		legacy := table + "_legacy"
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s", legacy)).Error; err != nil {
+				return err
+			}
+			if err := tx.Exec(fmt.Sprintf("CREATE TABLE %s AS SELECT * FROM %s", legacy, table)).Error; err != nil {
+				return err
+			}
+			return tx.Exec(fmt.Sprintf("DROP TABLE %s", table)).Error
+		})
+		if err != nil {
+			fmt.Println("migrate profiles:", err)
+			continue
+		}
+		moved = append(moved, table)
+	}
+	return moved
+}
+
+// migrateProfiles creates the default profile, gives it all data from before
+// profiles existed and restores the active profile.
+func (a *App) migrateProfiles(moved []string) {
+	var profile Profile
+	if err := a.db.First(&profile, defaultProfileID).Error; err != nil {
+		a.db.Create(&Profile{ID: defaultProfileID, Name: "默认用户"})
+	}
+
+	for _, table := range moved {
+		legacy := table + "_legacy"
+		columns, err := a.db.Migrator().ColumnTypes(legacy)
+		if err != nil {
+			continue
+		}
+		names := make([]string, 0, len(columns))
+		for _, c := range columns {
+			names = append(names, c.Name())
+		}
+		list := strings.Join(names, ", ")
+		err = a.db.Exec(fmt.Sprintf("INSERT INTO %s (profile_id, %s) SELECT ?, %s FROM %s", table, list, list, legacy), defaultProfileID).Error
+		if err != nil {
+			fmt.Println("migrate profiles:", err)
+			continue
+		}
+		a.db.Exec(fmt.Sprintf("DROP TABLE %s", legacy))
+	}
+	for _, model := range []interface{}{&Attempt{}, &ExamSession{}, &KeyChange{}, &ChatMessage{}} {
+		a.db.Model(model).Where("profile_id = 0 OR profile_id IS NULL").Update("profile_id", defaultProfileID)
+	}
+
+	a.activeProfile = defaultProfileID
+	if id, err := strconv.Atoi(a.getSetting("active_profile", "")); err == nil {
+		if a.db.First(&Profile{}, id).Error == nil {
+			a.activeProfile = uint(id)
+		}
+	}
+}
+
+func (a *App) ListProfiles() []Profile {
+	profiles := []Profile{}
+	a.db.Order("id ASC").Find(&profiles)
+	return profiles
+}
+
+func (a *App) GetActiveProfile() Profile {
+	var profile Profile
+	a.db.First(&profile, a.activeProfile)
+	return profile
+}
+
+// CreateProfile adds a learner with an empty progress row for every question.
+func (a *App) CreateProfile(name string) (Profile, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Profile{}, errors.New("用户名不能为空")
+	}
+	var count int64
+	a.db.Model(&Profile{}).Where("name = ?", name).Count(&count)
+	if count > 0 {
+		return Profile{}, errors.New("用户名已存在")
+	}
+
+	profile := Profile{Name: name}
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&profile).Error; err != nil {
+			return err
+		}
+		return tx.Exec("INSERT INTO user_progresses (profile_id, question_id, status, user_answer, is_marked, key_changed) SELECT ?, id, 0, '', false, false FROM questions", profile.ID).Error
+	})
+	return profile, err
+}
+
+func (a *App) RenameProfile(id uint, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("用户名不能为空")
+	}
+	var count int64
+	a.db.Model(&Profile{}).Where("name = ? AND id <> ?", name, id).Count(&count)
+	if count > 0 {
+		return errors.New("用户名已存在")
+	}
+	return a.db.Model(&Profile{}).Where("id = ?", id).Update("name", name).Error
+}
+
+// DeleteProfile removes a learner and all of their learning data.
+func (a *App) DeleteProfile(id uint) error {
+	if id == a.activeProfile {
+		return errors.New("不能删除正在使用的用户")
+	}
+	var profile Profile
+	if err := a.db.First(&profile, id).Error; err != nil {
+		return errors.New("用户不存在")
+	}
+
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		exams := tx.Model(&ExamSession{}).Select("id").Where("profile_id = ?", id)
+		if err := tx.Where("exam_id IN (?)", exams).Delete(&ExamItem{}).Error; err != nil {
+			return err
+		}
+		for _, model := range profileModels {
+			if err := tx.Where("profile_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&profile).Error
+	})
+}
+
+// SwitchProfile makes another learner active and ends every session of the current one.
+func (a *App) SwitchProfile(id uint) error {
+	var profile Profile
+	if err := a.db.First(&profile, id).Error; err != nil {
+		return errors.New("用户不存在")
+	}
+	if a.activeExam != 0 {
+		return errors.New("考试进行中，不能切换用户")
+	}
+
+	a.activeProfile = id
+	a.setSetting("active_profile", strconv.Itoa(int(id)))
+	a.MistakeMode = false
+	a.ReviewMode = false
+	a.mistakeSession = make(map[uint]int)
+	a.reviewSession = make(map[uint]int)
+	a.reviewQueue = nil
+	a.shownAt = make(map[uint]time.Time)
+	a.activeTag = 0
+	a.notesOnly = false
+
+	a.resumeExam()
+	// Notes are indexed for the active profile only
+	a.rebuildSearchIndex()
+	return nil
+}
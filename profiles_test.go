@@ -0,0 +1,102 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// TestMigrateProfiles opens a database from before profiles and checks that
+// its learning data ends up with the default profile.
+func TestMigrateProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "legacy.db")
+	db, err := gorm.Open(sqlite.Open(path), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, stmt := range []string{
+		"CREATE TABLE user_progresses (question_id integer PRIMARY KEY, status integer, user_answer text, is_marked numeric)",
+		"CREATE TABLE mistake_books (question_id integer PRIMARY KEY, count integer)",
+		"CREATE TABLE notes (question_id integer PRIMARY KEY, body text, created_at datetime, updated_at datetime)",
+		"CREATE TABLE attempts (id integer PRIMARY KEY, question_id integer, answer text, correct numeric)",
+		"INSERT INTO user_progresses VALUES (1, 2, 'C', 1), (2, 1, 'B', 0)",
+		"INSERT INTO mistake_books VALUES (1, 3)",
+		"INSERT INTO notes VALUES (2, '记一下', '2024-01-01 00:00:00', '2024-01-01 00:00:00')",
+		"INSERT INTO attempts VALUES (1, 1, 'C', 0)",
+	} {
+		if err := db.Exec(stmt).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	closeDB(db)
+
+	t.Setenv("QUIZ_DB_PATH", path)
+	a := NewApp()
+	if err := a.initDB(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { closeDB(a.db) })
+
+	if a.activeProfile != defaultProfileID {
+		t.Errorf("active profile = %d; want %d", a.activeProfile, defaultProfileID)
+	}
+	if profiles := a.ListProfiles(); len(profiles) != 1 || profiles[0].ID != defaultProfileID {
+		t.Fatalf("profiles = %+v; want only the default profile", profiles)
+	}
+
+	var p UserProgress
+	if err := a.db.First(&p, "profile_id = ? AND question_id = ?", defaultProfileID, 1).Error; err != nil {
+		t.Fatal(err)
+	}
+	if p.Status != 2 || p.UserAnswer != "C" || !p.IsMarked {
+		t.Errorf("progress of question 1 = %+v; want status 2, answer C, marked", p)
+	}
+	var m MistakeBook
+	if err := a.db.First(&m, "profile_id = ? AND question_id = ?", defaultProfileID, 1).Error; err != nil || m.Count != 3 {
+		t.Errorf("mistake of question 1 = %+v, %v; want count 3", m, err)
+	}
+	var n Note
+	if err := a.db.First(&n, "profile_id = ? AND question_id = ?", defaultProfileID, 2).Error; err != nil || n.Body != "记一下" {
+		t.Errorf("note of question 2 = %+v, %v; want 记一下", n, err)
+	}
+	var attempt Attempt
+	if err := a.db.First(&attempt, 1).Error; err != nil || attempt.ProfileID != defaultProfileID {
+		t.Errorf("attempt 1 = %+v, %v; want profile %d", attempt, err, defaultProfileID)
+	}
+
+	for _, table := range keyedProfileTables {
+		if a.db.Migrator().HasTable(table + "_legacy") {
+			t.Errorf("%s_legacy is still there", table)
+		}
+	}
+	var stray int64
+	a.db.Model(&UserProgress{}).Where("profile_id <> ?", defaultProfileID).Count(&stray)
+	if stray != 0 {
+		t.Errorf("%d progress rows outside the default profile", stray)
+	}
+}
+
+// TestMigrateProfilesKeepsActiveProfile checks that a restart keeps the
+// profile that was switched to, and that the migration runs only once.
+func TestMigrateProfilesKeepsActiveProfile(t *testing.T) {
+	a := newTestApp(t)
+	profile, err := a.CreateProfile("小明")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.SwitchProfile(profile.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	a.migrateProfiles(nil)
+	if a.activeProfile != profile.ID {
+		t.Errorf("active profile = %d; want %d", a.activeProfile, profile.ID)
+	}
+	var count int64
+	a.db.Model(&Profile{}).Count(&count)
+	if count != 2 {
+		t.Errorf("%d profiles; want 2", count)
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReviewCard holds the spaced-repetition state of a question (SM-2).
+type ReviewCard struct {
+	ProfileID    uint      `gorm:"primaryKey" json:"profile_id"`
+	QuestionID   uint      `gorm:"primaryKey" json:"question_id"`
+	Ease         float64   `json:"ease"`
+	Interval     int       `json:"interval"` // Days
+	Repetitions  int       `json:"repetitions"`
+	Lapses       int       `json:"lapses"`
+	Due          time.Time `gorm:"index" json:"due"`
+	LastReviewed time.Time `json:"last_reviewed"`
+}
+
+const defaultEase = 2.5
+
+// Scheduler implements SM-2. Now is injectable so scheduling can be tested
+// without waiting for real days to pass.
+type Scheduler struct {
+	Now func() time.Time
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{Now: time.Now}
+}
+
+// Quality maps an answer to an SM-2 grade (0-5).
+// Wrong answers are a lapse, quick correct answers count as easy recall.
+func (s *Scheduler) Quality(correct bool, durationMs int64) int {
+	if !correct {
+		return 1
+	}
+	if durationMs > 0 && durationMs < 10*1000 {
+		return 5
+	}
+	return 4
+}
+
+// Review returns the card after a review with the given quality.
+func (s *Scheduler) Review(card ReviewCard, quality int) ReviewCard {
+	now := s.Now()
+	if card.Ease == 0 {
+		card.Ease = defaultEase
+	}
+
+	if quality < 3 {
+		card.Repetitions = 0
+		card.Interval = 1
+		card.Lapses++
+	} else {
+		card.Repetitions++
+		switch card.Repetitions {
+		case 1:
+			card.Interval = 1
+		case 2:
+			card.Interval = 6
+		default:
+			card.Interval = int(math.Round(float64(card.Interval) * card.Ease))
+		}
+	}
+
+	q := float64(5 - quality)
+	card.Ease += 0.1 - q*(0.08+q*0.02)
+	if card.Ease < 1.3 {
+		card.Ease = 1.3
+	}
+
+	card.LastReviewed = now
+	card.Due = now.AddDate(0, 0, card.Interval)
+	return card
+}
+
+// IsDue reports whether the card should be shown in today's review.
+func (s *Scheduler) IsDue(card ReviewCard) bool {
+	return !card.Due.After(s.Now())
+}
+
+func (a *App) updateReviewCard(id uint, correct bool, durationMs int64) {
+	var card ReviewCard
+	if err := a.mine().First(&card, "question_id = ?", id).Error; err != nil {
+		card = ReviewCard{ProfileID: a.activeProfile, QuestionID: id, Ease: defaultEase}
+	}
+	card = a.scheduler.Review(card, a.scheduler.Quality(correct, durationMs))
+	a.db.Save(&card)
+}
+
+// seedReviewCards makes existing mistake-book entries due immediately,
+// so the first review session is not empty after upgrading.
+func (a *App) seedReviewCards() {
+	var mistakes []MistakeBook
+	a.db.Where("NOT EXISTS (SELECT 1 FROM review_cards WHERE review_cards.profile_id = mistake_books.profile_id AND review_cards.question_id = mistake_books.question_id)").Find(&mistakes)
+	for _, m := range mistakes {
+		a.db.Create(&ReviewCard{
+			ProfileID:  m.ProfileID,
+			QuestionID: m.QuestionID,
+			Ease:       defaultEase,
+			Due:        a.scheduler.Now(),
+		})
+	}
+}
+
+type DueItem struct {
+	ID       uint      `json:"id"`
+	Type     string    `json:"type"`
+	Due      time.Time `json:"due"`
+	Interval int       `json:"interval"`
+	Ease     float64   `json:"ease"`
+	Lapses   int       `json:"lapses"`
+}
+
+// GetDueQuestions returns questions due for review, most overdue first.
+func (a *App) GetDueQuestions(limit int) []DueItem {
+	query := a.db.Table("review_cards").
+		Select("review_cards.question_id AS id, questions.type, review_cards.due, review_cards.interval, review_cards.ease, review_cards.lapses").
+		Joins("JOIN questions ON questions.id = review_cards.question_id").
+		Where("review_cards.profile_id = ? AND review_cards.due <= ?", a.activeProfile, a.scheduler.Now()).
+		Where("questions.id IN (?)", a.practiceQuestionIDs()).
+		Order("review_cards.due ASC")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	items := []DueItem{}
+	query.Scan(&items)
+	return items
+}
+
+// GetNextDue returns the most overdue question, or nil when nothing is due.
+func (a *App) GetNextDue() *DueItem {
+	items := a.GetDueQuestions(1)
+	if len(items) == 0 {
+		return nil
+	}
+	return &items[0]
+}
+
+type ReviewSummary struct {
+	DueNow   int64 `json:"due_now"`
+	DueToday int64 `json:"due_today"`
+	Total    int64 `json:"total"`
+}
+
+func (a *App) GetReviewSummary() ReviewSummary {
+	now := a.scheduler.Now()
+	endOfDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location())
+
+	var s ReviewSummary
+	cards := a.mine().Model(&ReviewCard{}).Where("question_id IN (?)", a.practiceQuestionIDs())
+	cards.Session(&gorm.Session{}).Where("due <= ?", now).Count(&s.DueNow)
+	cards.Session(&gorm.Session{}).Where("due < ?", endOfDay).Count(&s.DueToday)
+	cards.Session(&gorm.Session{}).Count(&s.Total)
+	return s
+}
+
+// SetReviewMode starts or ends a review session. The due list is captured
+// when the session starts so answered questions stay visible until it ends.
+func (a *App) SetReviewMode(enable bool, limit int) {
+	a.ReviewMode = enable
+	a.reviewSession = make(map[uint]int)
+	a.reviewQueue = nil
+	if enable {
+		a.MistakeMode = false
+		for _, item := range a.GetDueQuestions(limit) {
+			a.reviewQueue = append(a.reviewQueue, item.ID)
+		}
+	}
+}
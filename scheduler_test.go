@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func fixedScheduler(now time.Time) *Scheduler {
+	return &Scheduler{Now: func() time.Time { return now }}
+}
+
+func TestSchedulerReview(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.Local)
+	s := fixedScheduler(now)
+
+	// A new card through two good reviews, an easy one, then lapses
+	steps := []struct {
+		quality     int
+		interval    int
+		ease        float64
+		repetitions int
+		lapses      int
+	}{
+		{4, 1, 2.5, 1, 0},
+		{4, 6, 2.5, 2, 0},
+		{5, 15, 2.6, 3, 0},
+		{1, 1, 2.06, 0, 1},
+		{1, 1, 1.52, 0, 2},
+		{1, 1, 1.3, 0, 3}, // Ease never drops below 1.3
+		{4, 1, 1.3, 1, 3},
+	}
+	card := ReviewCard{}
+	for i, step := range steps {
+		card = s.Review(card, step.quality)
+		if card.Interval != step.interval || card.Repetitions != step.repetitions || card.Lapses != step.lapses {
+			t.Fatalf("step %d: interval %d, repetitions %d, lapses %d; want %d, %d, %d",
+				i, card.Interval, card.Repetitions, card.Lapses, step.interval, step.repetitions, step.lapses)
+		}
+		if math.Abs(card.Ease-step.ease) > 1e-9 {
+			t.Fatalf("step %d: ease %v; want %v", i, card.Ease, step.ease)
+		}
+		if want := now.AddDate(0, 0, step.interval); !card.Due.Equal(want) || !card.LastReviewed.Equal(now) {
+			t.Fatalf("step %d: due %v, last reviewed %v; want %v, %v", i, card.Due, card.LastReviewed, want, now)
+		}
+	}
+}
+
+func TestSchedulerIsDue(t *testing.T) {
+	now := time.Date(2024, 3, 1, 9, 0, 0, 0, time.Local)
+	card := fixedScheduler(now).Review(ReviewCard{}, 4)
+
+	if fixedScheduler(now).IsDue(card) {
+		t.Error("card is due right after a review")
+	}
+	if !fixedScheduler(now.AddDate(0, 0, 1)).IsDue(card) {
+		t.Error("card is not due after its interval")
+	}
+}
+
+func TestSchedulerQuality(t *testing.T) {
+	s := NewScheduler()
+	tests := []struct {
+		correct    bool
+		durationMs int64
+		want       int
+	}{
+		{false, 3000, 1},
+		{true, 3000, 5},
+		{true, 30000, 4},
+		{true, 0, 4}, // Unknown duration
+	}
+	for _, tt := range tests {
+		if got := s.Quality(tt.correct, tt.durationMs); got != tt.want {
+			t.Errorf("Quality(%v, %d) = %d; want %d", tt.correct, tt.durationMs, got, tt.want)
+		}
+	}
+}
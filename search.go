@@ -0,0 +1,321 @@
+package main
+
+import (
+	"encoding/json"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// The FTS5 table holds pre-tokenized text: runs of CJK characters are split
+// into overlapping bigrams, so "脱贫攻坚" is indexed as "脱贫 贫攻 攻坚" and
+// a query becomes a phrase of the same bigrams.
+const createSearchTable = `CREATE VIRTUAL TABLE IF NOT EXISTS question_fts USING fts5(
+	content, options, explanation, ai_explanation, note, tokenize = 'unicode61'
+)`
+
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
+
+// ngramTokens splits text into CJK bigrams and lower-cased latin/number words.
+func ngramTokens(s string) []string {
+	var tokens []string
+	var run []rune
+	var word strings.Builder
+
+	flushRun := func() {
+		if len(run) == 1 {
+			tokens = append(tokens, string(run))
+		}
+		for i := 0; i+1 < len(run); i++ {
+			tokens = append(tokens, string(run[i:i+2]))
+		}
+		run = run[:0]
+	}
+	flushWord := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, strings.ToLower(word.String()))
+			word.Reset()
+		}
+	}
+
+	for _, r := range s {
+		switch {
+		case isCJK(r):
+			flushWord()
+			run = append(run, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushRun()
+			word.WriteRune(r)
+		default:
+			flushRun()
+			flushWord()
+		}
+	}
+	flushRun()
+	flushWord()
+	return tokens
+}
+
+func ngramText(s string) string {
+	return strings.Join(ngramTokens(s), " ")
+}
+
+func optionsText(options string) string {
+	var opts []string
+	json.Unmarshal([]byte(options), &opts)
+	return strings.Join(opts, "\n")
+}
+
+// searchIndexVersion is bumped when the columns or the tokenizer change, so
+// the index is dropped and rebuilt once instead of on every start.
+const searchIndexVersion = "1"
+
+func (a *App) initSearch() {
+	stale := a.getSetting("search_index_version", "") != searchIndexVersion
+	if stale {
+		a.db.Exec("DROP TABLE IF EXISTS question_fts")
+	}
+	a.ftsReady = a.db.Exec(createSearchTable).Error == nil
+	if a.ftsReady && stale {
+		a.rebuildSearchIndex()
+		a.setSetting("search_index_version", searchIndexVersion)
+	}
+}
+
+// rebuildSearchIndex re-indexes every question. It is cheap enough for a few
+// thousand questions to run after each bank sync.
+func (a *App) rebuildSearchIndex() {
+	if !a.ftsReady {
+		return
+	}
+	var questions []Question
+	a.db.Find(&questions)
+	var notes []Note
+	a.mine().Find(&notes)
+	bodies := make(map[uint]string, len(notes))
+	for _, n := range notes {
+		bodies[n.QuestionID] = n.Body
+	}
+
+	a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM question_fts").Error; err != nil {
+			return err
+		}
+		for _, q := range questions {
+			if err := indexQuestion(tx, q, bodies[q.ID]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func indexQuestion(tx *gorm.DB, q Question, note string) error {
+	return tx.Exec("INSERT INTO question_fts (rowid, content, options, explanation, ai_explanation, note) VALUES (?, ?, ?, ?, ?, ?)",
+		q.ID, ngramText(q.Content), ngramText(optionsText(q.Options)), ngramText(q.Explanation), ngramText(q.AIExplanation), ngramText(note)).Error
+}
+
+// reindexQuestion refreshes a single question after its text changed.
+func (a *App) reindexQuestion(q Question) {
+	if !a.ftsReady {
+		return
+	}
+	a.db.Exec("DELETE FROM question_fts WHERE rowid = ?", q.ID)
+	indexQuestion(a.db, q, a.GetNote(q.ID).Body)
+}
+
+type SearchFilters struct {
+	Type     string `json:"type"`     // Empty for any type
+	Status   *int   `json:"status"`   // Nil for any status
+	Marked   bool   `json:"marked"`   // Only marked questions
+	Mistakes bool   `json:"mistakes"` // Only questions in the mistake book
+	Notes    bool   `json:"notes"`    // Only questions with a note
+	Limit    int    `json:"limit"`
+}
+
+type SearchResult struct {
+	ID       uint   `json:"id"`
+	Number   uint   `json:"number"`
+	Type     string `json:"type"`
+	Status   int    `json:"status"`
+	IsMarked bool   `json:"is_marked"`
+	Field    string `json:"field"`   // content, options, explanation, ai_explanation, note
+	Snippet  string `json:"snippet"` // HTML with <mark> around matches
+}
+
+// ftsQuery turns user input into an FTS5 expression: every term becomes a
+// phrase of its bigrams and all terms must match. It returns false when a
+// term cannot be expressed with bigrams (a lone CJK character).
+func ftsQuery(query string) (string, bool) {
+	var phrases []string
+	for _, term := range strings.Fields(query) {
+		tokens := ngramTokens(term)
+		if len(tokens) == 0 {
+			continue
+		}
+		if len(tokens) == 1 && utf8.RuneCountInString(tokens[0]) == 1 && isCJK([]rune(tokens[0])[0]) {
+			return "", false
+		}
+		phrases = append(phrases, `"`+strings.Join(tokens, " ")+`"`)
+	}
+	return strings.Join(phrases, " AND "), len(phrases) > 0
+}
+
+// SearchQuestions finds questions in the active bank by text in the stem,
+// options, explanation, AI explanation and the user's note.
+func (a *App) SearchQuestions(query string, filters SearchFilters) []SearchResult {
+	query = strings.TrimSpace(query)
+	results := []SearchResult{}
+	if query == "" {
+		return results
+	}
+	if filters.Limit <= 0 {
+		filters.Limit = 100
+	}
+
+	tx := a.db.Table("questions").
+		Select("questions.*").
+		Joins("LEFT JOIN user_progresses ON user_progresses.question_id = questions.id AND user_progresses.profile_id = ?", a.activeProfile).
+		Where("questions.bank_id = ? AND questions.retired = ?", a.activeBank, false)
+
+	if match, ok := ftsQuery(query); ok && a.ftsReady {
+		tx = tx.Joins("JOIN question_fts ON question_fts.rowid = questions.id").
+			Where("question_fts MATCH ?", match).
+			Order("bm25(question_fts)")
+	} else {
+		// Single characters or no FTS5: plain substring search
+		for _, term := range strings.Fields(query) {
+			like := "%" + term + "%"
+			tx = tx.Where("(questions.content LIKE ? OR questions.options LIKE ? OR questions.explanation LIKE ? OR questions.ai_explanation LIKE ? OR questions.id IN (?))",
+				like, like, like, like, a.mine().Model(&Note{}).Select("question_id").Where("body LIKE ?", like))
+		}
+		tx = tx.Order("questions.number ASC")
+	}
+
+	if filters.Type != "" {
+		tx = tx.Where("questions.type = ?", filters.Type)
+	}
+	if filters.Status != nil {
+		tx = tx.Where("COALESCE(user_progresses.status, 0) = ?", *filters.Status)
+	}
+	if filters.Marked {
+		tx = tx.Where("user_progresses.is_marked = ?", true)
+	}
+	if filters.Mistakes {
+		tx = tx.Where("questions.id IN (?)", a.mine().Model(&MistakeBook{}).Select("question_id"))
+	}
+	if filters.Notes {
+		tx = tx.Where("questions.id IN (?)", a.mine().Model(&Note{}).Select("question_id"))
+	}
+
+	var questions []Question
+	tx.Limit(filters.Limit).Find(&questions)
+
+	for _, q := range questions {
+		var p UserProgress
+		a.mine().First(&p, "question_id = ?", q.ID)
+		field, snippet := matchSnippet(q, a.GetNote(q.ID).Body, query)
+		results = append(results, SearchResult{
+			ID:       q.ID,
+			Number:   q.Number,
+			Type:     q.Type,
+			Status:   p.Status,
+			IsMarked: p.IsMarked,
+			Field:    field,
+			Snippet:  snippet,
+		})
+	}
+	return results
+}
+
+// matchSnippet finds the first field containing a query term and returns an
+// escaped excerpt around it with all terms highlighted.
+func matchSnippet(q Question, note string, query string) (string, string) {
+	fields := []struct{ name, text string }{
+		{"content", q.Content},
+		{"options", optionsText(q.Options)},
+		{"explanation", q.Explanation},
+		{"ai_explanation", q.AIExplanation},
+		{"note", note},
+	}
+	terms := termPatterns(query)
+
+	// Match on the original text, case folding can change byte lengths
+	for _, f := range fields {
+		for _, re := range terms {
+			if loc := re.FindStringIndex(f.text); loc != nil {
+				from := utf8.RuneCountInString(f.text[:loc[0]])
+				to := from + utf8.RuneCountInString(f.text[loc[0]:loc[1]])
+				return f.name, highlight(excerpt(f.text, from, to), terms)
+			}
+		}
+	}
+	return "content", highlight(excerpt(q.Content, 0, 0), terms)
+}
+
+// termPatterns matches each query term case-insensitively.
+func termPatterns(query string) []*regexp.Regexp {
+	var patterns []*regexp.Regexp
+	for _, term := range strings.Fields(query) {
+		patterns = append(patterns, regexp.MustCompile("(?i)"+regexp.QuoteMeta(term)))
+	}
+	return patterns
+}
+
+// excerpt cuts about 30 runes of context around the runes [from, to).
+func excerpt(text string, from, to int) string {
+	const context = 30
+	runes := []rune(text)
+
+	prefix, suffix := "", ""
+	if from > context {
+		from -= context
+		prefix = "…"
+	} else {
+		from = 0
+	}
+	if to+context < len(runes) {
+		to += context
+		suffix = "…"
+	} else {
+		to = len(runes)
+	}
+	return prefix + string(runes[from:to]) + suffix
+}
+
+// highlight escapes text and wraps every match of any term in <mark>. The
+// matches are found on the raw text and merged so tags never nest or split
+// an entity.
+func highlight(text string, terms []*regexp.Regexp) string {
+	var ranges [][]int
+	for _, re := range terms {
+		ranges = append(ranges, re.FindAllStringIndex(text, -1)...)
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+
+	var sb strings.Builder
+	pos := 0
+	for i := 0; i < len(ranges); {
+		start, end := ranges[i][0], ranges[i][1]
+		for i++; i < len(ranges) && ranges[i][0] <= end; i++ {
+			end = max(end, ranges[i][1])
+		}
+		if end <= pos {
+			continue
+		}
+		start = max(start, pos)
+		sb.WriteString(html.EscapeString(text[pos:start]))
+		sb.WriteString("<mark>" + html.EscapeString(text[start:end]) + "</mark>")
+		pos = end
+	}
+	sb.WriteString(html.EscapeString(text[pos:]))
+	return sb.String()
+}
@@ -0,0 +1,51 @@
+package main
+
+import "testing"
+
+func TestMatchSnippet(t *testing.T) {
+	tests := []struct {
+		content, query    string
+		wantField, wanted string
+	}{
+		// "Ⱥ" lowercases to a longer rune, offsets must come from the original text
+		{"ȺȺȺ xyz", "xyz", "content", "ȺȺȺ <mark>xyz</mark>"},
+		{"a & b <mark> lt", "a mark amp lt", "content", "<mark>a</mark> &amp; b &lt;<mark>mark</mark>&gt; <mark>lt</mark>"},
+		{"脱贫攻坚精神", "攻坚 贫攻", "content", "脱<mark>贫攻坚</mark>精神"},
+	}
+	for _, tt := range tests {
+		field, snippet := matchSnippet(Question{Content: tt.content, Options: "[]"}, "", tt.query)
+		if field != tt.wantField || snippet != tt.wanted {
+			t.Errorf("matchSnippet(%q, %q) = %q, %q; want %q, %q", tt.content, tt.query, field, snippet, tt.wantField, tt.wanted)
+		}
+	}
+}
+
+func TestMatchSnippetNote(t *testing.T) {
+	field, snippet := matchSnippet(Question{Content: "题目", Options: `["A、 甲"]`}, "记住 Tip", "tip")
+	if field != "note" || snippet != "记住 <mark>Tip</mark>" {
+		t.Errorf("got %q, %q", field, snippet)
+	}
+}
+
+func TestSearchStatusFilter(t *testing.T) {
+	a := newTestApp(t)
+	all := a.SearchQuestions("脱贫", SearchFilters{})
+	if len(all) < 2 {
+		t.Fatalf("%d results; want at least 2", len(all))
+	}
+	if _, err := a.SubmitAnswer(all[0].ID, "Z"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Omitting the status must not narrow the results to unanswered questions
+	if got := a.SearchQuestions("脱贫", SearchFilters{}); len(got) != len(all) {
+		t.Errorf("%d results without a status filter; want %d", len(got), len(all))
+	}
+	wrong, unanswered := 2, 0
+	if got := a.SearchQuestions("脱贫", SearchFilters{Status: &wrong}); len(got) != 1 || got[0].ID != all[0].ID {
+		t.Errorf("wrong answers: %v; want only %d", got, all[0].ID)
+	}
+	if got := a.SearchQuestions("脱贫", SearchFilters{Status: &unanswered}); len(got) != len(all)-1 {
+		t.Errorf("%d unanswered results; want %d", len(got), len(all)-1)
+	}
+}
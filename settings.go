@@ -0,0 +1,25 @@
+package main
+
+// Setting is a simple key/value store for user preferences.
+type Setting struct {
+	Key   string `gorm:"primaryKey" json:"key"`
+	Value string `json:"value"`
+}
+
+func (a *App) getSetting(key string, fallback string) string {
+	var s Setting
+	if err := a.db.First(&s, "key = ?", key).Error; err != nil {
+		return fallback
+	}
+	return s.Value
+}
+
+func (a *App) setSetting(key string, value string) {
+	a.db.Save(&Setting{Key: key, Value: value})
+}
+
+func (a *App) loadSettings() {
+	if policy := a.getSetting("grading_policy", PolicyStrict); validPolicy(policy) {
+		a.grader.MultiPolicy = policy
+	}
+}
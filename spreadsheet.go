@@ -0,0 +1,583 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+	"golang.org/x/text/encoding/simplifiedchinese"
+
+	"quiz-app/internal/quizbank"
+)
+
+// ColumnMapping maps spreadsheet header names to question fields.
+// An empty name leaves the field unmapped.
+type ColumnMapping struct {
+	ID          string   `json:"id"`   // Required, resyncs match questions by it
+	Type        string   `json:"type"` // Guessed from the answer if unmapped or empty
+	Content     string   `json:"content"`
+	Options     []string `json:"options"` // Columns of options A to H in order
+	Answer      string   `json:"answer"`
+	Explanation string   `json:"explanation"`
+	Tags        string   `json:"tags"` // Separated by commas, semicolons or 、
+}
+
+// RowError is a validation error of one spreadsheet row.
+type RowError struct {
+	Row     int    `json:"row"` // Row number as shown in the spreadsheet
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e RowError) String() string {
+	if e.Column != "" {
+		return fmt.Sprintf("第 %d 行「%s」: %s", e.Row, e.Column, e.Message)
+	}
+	return fmt.Sprintf("第 %d 行: %s", e.Row, e.Message)
+}
+
+type SpreadsheetPreview struct {
+	Path    string        `json:"path"`
+	Headers []string      `json:"headers"`
+	Rows    [][]string    `json:"rows"`  // The first few data rows
+	Total   int           `json:"total"` // Number of data rows
+	Mapping ColumnMapping `json:"mapping"`
+	Errors  []RowError    `json:"errors"` // Validation with the guessed mapping
+}
+
+const previewRows = 5
+
+func isSpreadsheet(p string) bool {
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".csv", ".xlsx":
+		return true
+	}
+	return false
+}
+
+// readTable returns all rows of a CSV file or the first sheet of an XLSX file.
+func readTable(p string) ([][]string, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("读取表格文件失败: %w", err)
+	}
+	var rows [][]string
+	switch strings.ToLower(filepath.Ext(p)) {
+	case ".csv":
+		rows, err = readCSV(data)
+	case ".xlsx":
+		rows, err = readXLSX(data)
+	default:
+		return nil, errors.New("只支持 .csv 和 .xlsx 文件")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("表格中没有数据")
+	}
+	return rows, nil
+}
+
+func readCSV(data []byte) ([][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	// Excel on Chinese Windows saves CSV as GBK
+	if !utf8.Valid(data) {
+		decoded, err := simplifiedchinese.GB18030.NewDecoder().Bytes(data)
+		if err != nil {
+			return nil, errors.New("无法识别 CSV 文件编码，请另存为 UTF-8")
+		}
+		data = decoded
+	}
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("CSV 文件格式错误: %w", err)
+	}
+	return rows, nil
+}
+
+type xlsxRels struct {
+	Rels []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// xlsxText is a shared or inline string, either plain or made of rich text runs.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string    `xml:"r,attr"`
+			Type   string    `xml:"t,attr"`
+			Value  string    `xml:"v"`
+			Inline *xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX reads the cell text of the first sheet. Only what bank
+// spreadsheets need is supported: strings, numbers and booleans.
+func readXLSX(data []byte) ([][]string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.New("不是有效的 xlsx 文件")
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	decode := func(name string, v interface{}) error {
+		f, ok := files[name]
+		if !ok {
+			return fmt.Errorf("xlsx 文件缺少 %s", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		if err := xml.NewDecoder(rc).Decode(v); err != nil {
+			return fmt.Errorf("xlsx 文件格式错误: %w", err)
+		}
+		return nil
+	}
+
+	var wb xlsxWorkbook
+	if err := decode("xl/workbook.xml", &wb); err != nil {
+		return nil, err
+	}
+	var rels xlsxRels
+	if err := decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	if len(wb.Sheets) == 0 {
+		return nil, errors.New("xlsx 文件中没有工作表")
+	}
+	sheetPath := ""
+	for _, r := range rels.Rels {
+		if r.ID == wb.Sheets[0].RID {
+			if strings.HasPrefix(r.Target, "/") {
+				sheetPath = strings.TrimPrefix(r.Target, "/")
+			} else {
+				sheetPath = path.Join("xl", r.Target)
+			}
+		}
+	}
+
+	var shared []string
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		var sst struct {
+			Items []xlsxText `xml:"si"`
+		}
+		if err := decode("xl/sharedStrings.xml", &sst); err != nil {
+			return nil, err
+		}
+		for _, si := range sst.Items {
+			shared = append(shared, si.String())
+		}
+	}
+
+	var sheet xlsxSheet
+	if err := decode(sheetPath, &sheet); err != nil {
+		return nil, err
+	}
+	var rows [][]string
+	for _, r := range sheet.Rows {
+		var row []string
+		for i, c := range r.Cells {
+			col := i
+			if c.Ref != "" {
+				col = cellColumn(c.Ref)
+			}
+			var v string
+			switch c.Type {
+			case "s":
+				if n, err := strconv.Atoi(c.Value); err == nil && n >= 0 && n < len(shared) {
+					v = shared[n]
+				}
+			case "inlineStr":
+				if c.Inline != nil {
+					v = c.Inline.String()
+				}
+			case "b":
+				v = map[string]string{"0": "FALSE", "1": "TRUE"}[c.Value]
+			default:
+				v = c.Value
+			}
+			for len(row) <= col {
+				row = append(row, "")
+			}
+			row[col] = v
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// cellColumn turns a cell reference like "AB12" into a 0-based column index.
+func cellColumn(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return col - 1
+}
+
+var (
+	optionHeader = regexp.MustCompile(`(?i)^(?:选项|option)?\s*([A-H])\s*(?:选项)?$`)
+	tagSeparator = regexp.MustCompile(`[,，;；、|]`)
+)
+
+// guessMapping maps headers by their usual names in Chinese and English.
+func guessMapping(headers []string) ColumnMapping {
+	names := map[string][]string{
+		"id":          {"题号", "序号", "编号", "id", "no"},
+		"type":        {"题型", "类型", "type"},
+		"content":     {"题目", "题干", "内容", "问题", "content", "question"},
+		"answer":      {"答案", "正确答案", "answer"},
+		"explanation": {"解析", "答案解析", "explanation"},
+		"tags":        {"标签", "章节", "知识点", "tags", "tag", "chapter"},
+	}
+	var m ColumnMapping
+	fields := map[string]*string{
+		"id": &m.ID, "type": &m.Type, "content": &m.Content, "answer": &m.Answer,
+		"explanation": &m.Explanation, "tags": &m.Tags,
+	}
+	options := make(map[int]string)
+	for _, h := range headers {
+		key := strings.ToLower(strings.TrimSpace(h))
+		if match := optionHeader.FindStringSubmatch(key); match != nil {
+			options[int(strings.ToUpper(match[1])[0]-'A')] = h
+			continue
+		}
+		for field, candidates := range names {
+			if *fields[field] == "" && contains(candidates, key) {
+				*fields[field] = h
+			}
+		}
+	}
+	for i := 0; i < 8; i++ {
+		name, ok := options[i]
+		if !ok {
+			break
+		}
+		m.Options = append(m.Options, name)
+	}
+	return m
+}
+
+var typeAliases = map[string]string{
+	"单选": "单选题", "单选题": "单选题", "单项选择题": "单选题",
+	"多选": "多选题", "多选题": "多选题", "多项选择题": "多选题",
+	"判断": "判断题", "判断题": "判断题",
+}
+
+// spreadsheetQuestions converts the data rows to bank questions and
+// validates every row. Blank rows are skipped.
+func spreadsheetQuestions(rows [][]string, m ColumnMapping) ([]quizbank.Question, []RowError) {
+	headers := rows[0]
+	index := make(map[string]int, len(headers))
+	for i, h := range headers {
+		if _, ok := index[h]; !ok {
+			index[h] = i
+		}
+	}
+
+	var errs []RowError
+	// Row numbers shift when rows are inserted, so resyncs need a stable ID column
+	if m.ID == "" || m.Content == "" || m.Answer == "" {
+		return nil, []RowError{{Row: 1, Message: "必须指定题号、题目和答案所在的列"}}
+	}
+	for _, name := range append([]string{m.ID, m.Type, m.Content, m.Answer, m.Explanation, m.Tags}, m.Options...) {
+		if _, ok := index[name]; name != "" && !ok {
+			errs = append(errs, RowError{Row: 1, Column: name, Message: "表头中没有这一列"})
+		}
+	}
+	if len(m.Options) > 8 {
+		errs = append(errs, RowError{Row: 1, Message: "最多支持 8 个选项"})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	var raws []quizbank.Question
+	seen := make(map[uint]int)
+	for i, row := range rows[1:] {
+		rowNo := i + 2
+		cell := func(name string) string {
+			if col, ok := index[name]; name != "" && ok && col < len(row) {
+				return strings.TrimSpace(row[col])
+			}
+			return ""
+		}
+		if strings.TrimSpace(strings.Join(row, "")) == "" {
+			continue
+		}
+		fail := func(column, format string, args ...interface{}) {
+			errs = append(errs, RowError{Row: rowNo, Column: column, Message: fmt.Sprintf(format, args...)})
+		}
+
+		rq := quizbank.Question{
+			Content:     cell(m.Content),
+			Answer:      cell(m.Answer),
+			Explanation: cell(m.Explanation),
+		}
+		if rq.Content == "" {
+			fail(m.Content, "缺少题目内容")
+		}
+		if rq.Answer == "" {
+			fail(m.Answer, "缺少答案")
+			continue
+		}
+
+		n, err := strconv.ParseUint(strings.TrimSuffix(cell(m.ID), ".0"), 10, 32)
+		if err != nil || n == 0 {
+			fail(m.ID, "题号必须是正整数")
+			continue
+		}
+		rq.ID = uint(n)
+		if prev, ok := seen[rq.ID]; ok {
+			fail(m.ID, "题号 %d 与第 %d 行重复", rq.ID, prev)
+			continue
+		}
+		seen[rq.ID] = rowNo
+
+		gap := false
+		for j, name := range m.Options {
+			text := cell(name)
+			if text == "" {
+				continue
+			}
+			label := string(rune('A' + j))
+			if len(rq.Options) != j {
+				fail(name, "选项 %s 前面有空选项", label)
+				gap = true
+				break
+			}
+			if !optionLabel.MatchString(text) {
+				text = label + "、 " + text
+			}
+			rq.Options = append(rq.Options, text)
+		}
+		if gap {
+			continue
+		}
+
+		if t := cell(m.Type); t != "" {
+			if rq.Type = typeAliases[t]; rq.Type == "" {
+				fail(m.Type, "无法识别的题型「%s」", t)
+				continue
+			}
+		} else {
+			rq.Type = guessType(rq.Answer, len(rq.Options))
+		}
+
+		letters := quizbank.AnswerLetters(rq.Type, rq.Answer)
+		if rq.Type == "判断题" {
+			if len(rq.Options) == 0 {
+				rq.Options = []string{"A、正确", "B、错误"}
+			}
+			if len(letters) != 1 || letters[0] > "B" {
+				fail(m.Answer, "判断题答案应为 对/错 或 A/B")
+				continue
+			}
+		} else if len(rq.Options) < 2 {
+			fail("", "选择题至少需要 2 个选项")
+			continue
+		}
+		if len(letters) == 0 {
+			fail(m.Answer, "无法识别的答案「%s」", rq.Answer)
+			continue
+		}
+		if last := letters[len(letters)-1]; int(last[0]-'A') >= len(rq.Options) {
+			fail(m.Answer, "答案 %s 超出选项范围", last)
+			continue
+		}
+		if rq.Type == "单选题" && len(letters) > 1 {
+			fail(m.Answer, "单选题只能有一个答案")
+			continue
+		}
+		rq.Answer = strings.Join(letters, ",")
+
+		for _, tag := range tagSeparator.Split(cell(m.Tags), -1) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				rq.Tags = append(rq.Tags, tag)
+			}
+		}
+		raws = append(raws, rq)
+	}
+	if len(errs) == 0 && len(raws) == 0 {
+		errs = append(errs, RowError{Row: 2, Message: "表格中没有题目"})
+	}
+	return raws, errs
+}
+
+// guessType infers the question type from an answer like "对", "B" or "ACD".
+// "F" is only read as an option letter when the row has an option F.
+func guessType(answer string, options int) string {
+	s := strings.ToUpper(strings.TrimSpace(answer))
+	if _, ok := quizbank.TFAliases[s]; ok && (len(s) != 1 || int(s[0]-'A') >= options) {
+		return "判断题"
+	}
+	if len(quizbank.AnswerLetters("多选题", s)) > 1 {
+		return "多选题"
+	}
+	return "单选题"
+}
+
+// spreadsheetBankJSON converts a spreadsheet to the questions.json format so
+// it goes through the same parse and sync as JSON banks.
+func spreadsheetBankJSON(p string, m ColumnMapping) ([]byte, []RowError, error) {
+	rows, err := readTable(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	raws, errs := spreadsheetQuestions(rows, m)
+	if len(errs) > 0 {
+		return nil, errs, rowErrorsError(errs)
+	}
+	data, err := json.Marshal(raws)
+	return data, nil, err
+}
+
+func rowErrorsError(errs []RowError) error {
+	msg := errs[0].String()
+	if len(errs) > 1 {
+		msg += fmt.Sprintf(" 等 %d 处错误", len(errs))
+	}
+	return errors.New(msg)
+}
+
+// PreviewSpreadsheet reads the headers and first rows of a CSV or XLSX file
+// and guesses the column mapping.
+func (a *App) PreviewSpreadsheet(p string) (SpreadsheetPreview, error) {
+	rows, err := readTable(p)
+	if err != nil {
+		return SpreadsheetPreview{}, err
+	}
+	preview := SpreadsheetPreview{
+		Path:    p,
+		Headers: rows[0],
+		Total:   len(rows) - 1,
+		Mapping: guessMapping(rows[0]),
+		Rows:    [][]string{},
+	}
+	for _, row := range rows[1:] {
+		if len(preview.Rows) == previewRows {
+			break
+		}
+		preview.Rows = append(preview.Rows, row)
+	}
+	_, preview.Errors = spreadsheetQuestions(rows, preview.Mapping)
+	return preview, nil
+}
+
+// CheckSpreadsheet validates every row with the given mapping.
+func (a *App) CheckSpreadsheet(p string, m ColumnMapping) ([]RowError, error) {
+	rows, err := readTable(p)
+	if err != nil {
+		return nil, err
+	}
+	_, errs := spreadsheetQuestions(rows, m)
+	if errs == nil {
+		errs = []RowError{}
+	}
+	return errs, nil
+}
+
+// ImportSpreadsheet adds a CSV or XLSX file as a new bank. Nothing is
+// imported while any row has errors.
+func (a *App) ImportSpreadsheet(p string, name string, m ColumnMapping) (Bank, error) {
+	data, _, err := spreadsheetBankJSON(p, m)
+	if err != nil {
+		return Bank{}, err
+	}
+	mapping, _ := json.Marshal(m)
+
+	if strings.TrimSpace(name) == "" {
+		name = strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))
+	}
+	bank := Bank{Name: name, Source: p, Mapping: string(mapping)}
+	if err := a.db.Create(&bank).Error; err != nil {
+		return Bank{}, err
+	}
+	if _, err := a.syncBank(bank.ID, data); err != nil {
+		a.db.Delete(&bank)
+		return Bank{}, err
+	}
+	a.db.First(&bank, bank.ID)
+	return bank, nil
+}
+
+// OpenSpreadsheetDialog lets the user pick a spreadsheet and previews it for
+// the mapping step. An empty path means the dialog was cancelled.
+func (a *App) OpenSpreadsheetDialog() (SpreadsheetPreview, error) {
+	p, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title:   "从表格导入题库",
+		Filters: []runtime.FileFilter{{DisplayName: "表格文件 (*.csv, *.xlsx)", Pattern: "*.csv;*.xlsx"}},
+	})
+	if err != nil || p == "" {
+		return SpreadsheetPreview{}, err
+	}
+	return a.PreviewSpreadsheet(p)
+}
+
+// readBankSource returns the questions.json data of an imported bank,
+// converting spreadsheet banks with their saved mapping.
+func readBankSource(bank Bank) ([]byte, error) {
+	if bank.Mapping == "" || !isSpreadsheet(bank.Source) {
+		data, err := os.ReadFile(bank.Source)
+		if err != nil {
+			return nil, fmt.Errorf("读取题库文件失败: %w", err)
+		}
+		return data, nil
+	}
+	var m ColumnMapping
+	if err := json.Unmarshal([]byte(bank.Mapping), &m); err != nil {
+		return nil, err
+	}
+	data, _, err := spreadsheetBankJSON(bank.Source, m)
+	return data, err
+}
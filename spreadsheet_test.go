@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestGuessType(t *testing.T) {
+	tests := []struct {
+		answer  string
+		options int
+		want    string
+	}{
+		{"对", 0, "判断题"},
+		{"✗", 0, "判断题"},
+		{"F", 0, "判断题"},
+		{"true", 0, "判断题"},
+		{"FALSE", 4, "判断题"},
+		{"F", 6, "单选题"},
+		{"B", 4, "单选题"},
+		{"ACD", 4, "多选题"},
+	}
+	for _, tt := range tests {
+		if got := guessType(tt.answer, tt.options); got != tt.want {
+			t.Errorf("guessType(%q, %d) = %q; want %q", tt.answer, tt.options, got, tt.want)
+		}
+	}
+}
+
+func TestSpreadsheetQuestionsNeedID(t *testing.T) {
+	rows := [][]string{{"题目", "答案"}, {"人民是历史的创造者", "对"}}
+	_, errs := spreadsheetQuestions(rows, ColumnMapping{Content: "题目", Answer: "答案"})
+	if len(errs) != 1 || errs[0].Row != 1 {
+		t.Fatalf("errs = %v; want a header error", errs)
+	}
+}
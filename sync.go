@@ -0,0 +1,203 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"quiz-app/internal/quizbank"
+)
+
+// BankVersion records every bank file version that was synced, with what changed.
+type BankVersion struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	BankID    uint      `gorm:"index" json:"bank_id"`
+	Hash      string    `json:"hash"`
+	Inserted  int       `json:"inserted"`
+	Updated   int       `json:"updated"`
+	Retired   int       `json:"retired"`
+	Restored  int       `json:"restored"`
+	KeyChange int       `json:"key_change"` // Questions whose answer key changed
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type SyncSummary struct {
+	BankID    uint   `json:"bank_id"`
+	Version   string `json:"version"`
+	Unchanged bool   `json:"unchanged"`
+	Inserted  int    `json:"inserted"`
+	Updated   int    `json:"updated"`
+	Retired   int    `json:"retired"`
+	Restored  int    `json:"restored"`
+	KeyChange int    `json:"key_change"`
+	Error     string `json:"error,omitempty"`
+}
+
+func fileHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// contentHash identifies the content of a question independent of its ID.
+func contentHash(qType, content, options, answer, explanation string) string {
+	sum := sha256.Sum256([]byte(qType + "\x00" + content + "\x00" + options + "\x00" + answer + "\x00" + explanation))
+	return hex.EncodeToString(sum[:])
+}
+
+func (q Question) hash() string {
+	return contentHash(q.Type, q.Content, q.Options, q.Answer, q.Explanation)
+}
+
+// syncBank brings a bank in line with its file: new questions are inserted,
+// changed ones updated and missing ones retired. Retired questions keep
+// their progress and come back if they reappear in a later version.
+func (a *App) syncBank(bankID uint, data []byte) (SyncSummary, error) {
+	summary := SyncSummary{BankID: bankID, Version: fileHash(data)}
+
+	var bank Bank
+	if err := a.db.First(&bank, bankID).Error; err != nil {
+		return summary, errors.New("题库不存在")
+	}
+	if bank.Version == summary.Version {
+		summary.Unchanged = true
+		return summary, nil
+	}
+
+	raws, err := parseBankJSON(data)
+	if err != nil {
+		return summary, err
+	}
+
+	err = a.db.Transaction(func(tx *gorm.DB) error {
+		var existing []Question
+		tx.Where("bank_id = ?", bankID).Find(&existing)
+		byNumber := make(map[uint]Question, len(existing))
+		for _, q := range existing {
+			byNumber[q.Number] = q
+		}
+
+		seen := make(map[uint]bool, len(raws))
+		var inserts []quizbank.Question
+		for _, rq := range raws {
+			seen[rq.ID] = true
+			q, ok := byNumber[rq.ID]
+			if !ok {
+				inserts = append(inserts, rq)
+				continue
+			}
+			if err := syncQuestionTags(tx, bankID, q.ID, rq.Tags); err != nil {
+				return err
+			}
+
+			next := toQuestion(rq, bankID)
+			changed := q.hash() != next.hash()
+			if !changed && !q.Retired && q.ContentHash != "" {
+				continue
+			}
+
+			oldAnswer := q.Answer
+			keyChanged := quizbank.NormalizeAnswer(q.Type, q.Answer) != quizbank.NormalizeAnswer(next.Type, next.Answer)
+			if q.Retired {
+				summary.Restored++
+			} else if changed {
+				summary.Updated++
+			}
+
+			q.Type = next.Type
+			q.Content = next.Content
+			q.Options = next.Options
+			q.Answer = next.Answer
+			q.Explanation = next.Explanation
+			q.ContentHash = next.hash()
+			q.Retired = false
+			if err := tx.Model(&q).Select("type", "content", "options", "answer", "explanation", "content_hash", "retired").Updates(&q).Error; err != nil {
+				return err
+			}
+
+			if keyChanged {
+				summary.KeyChange++
+				if err := a.onKeyChanged(tx, q, oldAnswer); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := insertQuestions(tx, bankID, inserts); err != nil {
+			return err
+		}
+		summary.Inserted = len(inserts)
+
+		for _, q := range existing {
+			if !seen[q.Number] && !q.Retired {
+				if err := tx.Model(&q).Update("retired", true).Error; err != nil {
+					return err
+				}
+				summary.Retired++
+			}
+		}
+
+		if err := tx.Model(&bank).Update("version", summary.Version).Error; err != nil {
+			return err
+		}
+		return tx.Create(&BankVersion{
+			BankID:    bankID,
+			Hash:      summary.Version,
+			Inserted:  summary.Inserted,
+			Updated:   summary.Updated,
+			Retired:   summary.Retired,
+			Restored:  summary.Restored,
+			KeyChange: summary.KeyChange,
+		}).Error
+	})
+	if err == nil {
+		a.rebuildSearchIndex()
+	}
+	return summary, err
+}
+
+// syncEmbeddedBank syncs the default bank with the questions.json built into the app.
+func (a *App) syncEmbeddedBank() {
+	summary, err := a.syncBank(defaultBankID, questionsJSON)
+	if err != nil {
+		summary.Error = err.Error()
+		fmt.Println("sync questions.json:", err)
+	}
+	a.lastSync = summary
+}
+
+// GetLastSyncSummary reports what the startup sync of the built-in bank changed.
+func (a *App) GetLastSyncSummary() SyncSummary {
+	return a.lastSync
+}
+
+// ResyncBank re-reads an imported bank from its source file.
+func (a *App) ResyncBank(id uint) (SyncSummary, error) {
+	var bank Bank
+	if err := a.db.First(&bank, id).Error; err != nil {
+		return SyncSummary{}, errors.New("题库不存在")
+	}
+	if id == defaultBankID {
+		a.syncEmbeddedBank()
+		if a.lastSync.Error != "" {
+			return a.lastSync, errors.New(a.lastSync.Error)
+		}
+		return a.lastSync, nil
+	}
+
+	data, err := readBankSource(bank)
+	if err != nil {
+		return SyncSummary{}, err
+	}
+	return a.syncBank(id, data)
+}
+
+// GetBankVersions returns the sync history of a bank, newest first.
+func (a *App) GetBankVersions(id uint) []BankVersion {
+	versions := []BankVersion{}
+	a.db.Where("bank_id = ?", id).Order("id DESC").Find(&versions)
+	return versions
+}
@@ -0,0 +1,69 @@
+package main
+
+import "testing"
+
+func TestSyncBankCounts(t *testing.T) {
+	a := newTestApp(t)
+	bank := Bank{Name: "测试"}
+	a.db.Create(&bank)
+
+	const (
+		q1  = `{"id": 1, "type": "单选题", "content": "甲", "options": ["A、1", "B、2"], "answer": "A"}`
+		q1b = `{"id": 1, "type": "单选题", "content": "甲乙", "options": ["A、1", "B、2"], "answer": "A"}`
+		q2  = `{"id": 2, "type": "单选题", "content": "乙", "options": ["A、1", "B、2"], "answer": "B"}`
+		q2b = `{"id": 2, "type": "单选题", "content": "乙", "options": ["A、1", "B、2"], "answer": "A"}`
+		q3  = `{"id": 3, "type": "判断题", "content": "丙", "options": ["A、正确", "B、错误"], "answer": "A"}`
+	)
+	// Each step syncs the next version of the same bank file
+	steps := []struct {
+		name string
+		data string
+		want SyncSummary
+		live int // Questions not retired afterwards
+	}{
+		{"first sync", "[" + q1 + "," + q2 + "]", SyncSummary{Inserted: 2}, 2},
+		{"same file", "[" + q1 + "," + q2 + "]", SyncSummary{Unchanged: true}, 2},
+		{"edit, drop and add", "[" + q1b + "," + q3 + "]", SyncSummary{Inserted: 1, Updated: 1, Retired: 1}, 2},
+		{"dropped question is back", "[" + q1b + "," + q2 + "," + q3 + "]", SyncSummary{Restored: 1}, 3},
+		{"answer key changed", "[" + q1b + "," + q2b + "," + q3 + "]", SyncSummary{Updated: 1, KeyChange: 1}, 3},
+		{"everything dropped", "[" + q3 + "]", SyncSummary{Retired: 2}, 1},
+	}
+
+	var firstIDs []uint
+	versions := 0
+	for _, step := range steps {
+		got, err := a.syncBank(bank.ID, []byte(step.data))
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		step.want.BankID = bank.ID
+		step.want.Version = fileHash([]byte(step.data))
+		if got != step.want {
+			t.Errorf("%s: summary = %+v; want %+v", step.name, got, step.want)
+		}
+		if !got.Unchanged {
+			versions++
+		}
+
+		var live int64
+		a.db.Model(&Question{}).Where("bank_id = ? AND retired = ?", bank.ID, false).Count(&live)
+		if int(live) != step.live {
+			t.Errorf("%s: %d live questions; want %d", step.name, live, step.live)
+		}
+		if firstIDs == nil {
+			a.db.Model(&Question{}).Where("bank_id = ?", bank.ID).Order("number ASC").Pluck("id", &firstIDs)
+		}
+	}
+
+	// Questions keep their IDs, and with them their progress, across versions
+	var ids []uint
+	a.db.Model(&Question{}).Where("bank_id = ? AND number IN ?", bank.ID, []uint{1, 2}).Order("number ASC").Pluck("id", &ids)
+	if len(ids) != 2 || ids[0] != firstIDs[0] || ids[1] != firstIDs[1] {
+		t.Errorf("question IDs = %v; want %v", ids, firstIDs)
+	}
+	var history []BankVersion
+	a.db.Where("bank_id = ?", bank.ID).Find(&history)
+	if len(history) != versions {
+		t.Errorf("%d bank versions recorded; want %d", len(history), versions)
+	}
+}
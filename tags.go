@@ -0,0 +1,220 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Tag is a chapter or topic inside a bank.
+type Tag struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	BankID    uint      `gorm:"uniqueIndex:idx_bank_tag" json:"bank_id"`
+	Name      string    `gorm:"uniqueIndex:idx_bank_tag" json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// QuestionTag links questions and tags. Links from the bank file are
+// replaced on every sync, links added by the user or accepted from AI
+// suggestions are kept.
+type QuestionTag struct {
+	QuestionID uint   `gorm:"primaryKey" json:"question_id"`
+	TagID      uint   `gorm:"primaryKey;index" json:"tag_id"`
+	Source     string `json:"source"` // bank, user, ai
+}
+
+// ensureTag returns the ID of the named tag in a bank, creating it if needed.
+func ensureTag(tx *gorm.DB, bankID uint, name string) (uint, error) {
+	tag := Tag{BankID: bankID, Name: name}
+	if err := tx.Where("bank_id = ? AND name = ?", bankID, name).FirstOrCreate(&tag).Error; err != nil {
+		return 0, err
+	}
+	return tag.ID, nil
+}
+
+// syncQuestionTags replaces the bank-file tags of a question.
+func syncQuestionTags(tx *gorm.DB, bankID uint, questionID uint, names []string) error {
+	if err := tx.Where("question_id = ? AND source = ?", questionID, "bank").Delete(&QuestionTag{}).Error; err != nil {
+		return err
+	}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		tagID, err := ensureTag(tx, bankID, name)
+		if err != nil {
+			return err
+		}
+		link := QuestionTag{QuestionID: questionID, TagID: tagID, Source: "bank"}
+		// A user link to the same tag wins
+		if err := tx.Where("question_id = ? AND tag_id = ?", questionID, tagID).FirstOrCreate(&link).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// practiceQuestionIDs narrows bankQuestionIDs to the tag being practised and,
+// if enabled, to questions with a note.
+func (a *App) practiceQuestionIDs() *gorm.DB {
+	ids := a.bankQuestionIDs()
+	if a.activeTag != 0 {
+		ids = ids.Where("id IN (?)", a.db.Model(&QuestionTag{}).Select("question_id").Where("tag_id = ?", a.activeTag))
+	}
+	if a.notesOnly {
+		ids = ids.Where("id IN (?)", a.mine().Model(&Note{}).Select("question_id"))
+	}
+	return ids
+}
+
+type TagView struct {
+	Tag
+	Count int64 `json:"count"`
+}
+
+// ListTags returns the tags of the active bank with their question counts.
+func (a *App) ListTags() []TagView {
+	views := []TagView{}
+	a.db.Table("tags").
+		Select("tags.*, COUNT(question_tags.question_id) AS count").
+		Joins("LEFT JOIN question_tags ON question_tags.tag_id = tags.id AND question_tags.question_id IN (?)", a.bankQuestionIDs()).
+		Where("tags.bank_id = ?", a.activeBank).
+		Group("tags.id").
+		Order("tags.name ASC").
+		Scan(&views)
+	return views
+}
+
+func (a *App) CreateTag(name string) (Tag, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Tag{}, errors.New("标签名称不能为空")
+	}
+	var count int64
+	a.db.Model(&Tag{}).Where("bank_id = ? AND name = ?", a.activeBank, name).Count(&count)
+	if count > 0 {
+		return Tag{}, errors.New("标签已存在")
+	}
+	tag := Tag{BankID: a.activeBank, Name: name}
+	err := a.db.Create(&tag).Error
+	return tag, err
+}
+
+func (a *App) RenameTag(id uint, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("标签名称不能为空")
+	}
+	var tag Tag
+	if err := a.db.First(&tag, id).Error; err != nil {
+		return errors.New("标签不存在")
+	}
+	var count int64
+	a.db.Model(&Tag{}).Where("bank_id = ? AND name = ? AND id <> ?", tag.BankID, name, id).Count(&count)
+	if count > 0 {
+		return errors.New("标签已存在")
+	}
+	return a.db.Model(&tag).Update("name", name).Error
+}
+
+func (a *App) DeleteTag(id uint) error {
+	err := a.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", id).Delete(&QuestionTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Tag{}, id).Error
+	})
+	if err == nil && a.activeTag == id {
+		a.activeTag = 0
+	}
+	return err
+}
+
+// TagQuestions adds a tag to the given questions.
+func (a *App) TagQuestions(tagID uint, ids []uint) error {
+	var tag Tag
+	if err := a.db.First(&tag, tagID).Error; err != nil {
+		return errors.New("标签不存在")
+	}
+	return a.db.Transaction(func(tx *gorm.DB) error {
+		for _, id := range ids {
+			var count int64
+			tx.Model(&Question{}).Where("id = ? AND bank_id = ?", id, tag.BankID).Count(&count)
+			if count == 0 {
+				return errors.New("题目不在该标签所属的题库中")
+			}
+			// Tagging by hand turns a bank link into a user link
+			if err := tx.Save(&QuestionTag{QuestionID: id, TagID: tagID, Source: "user"}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (a *App) UntagQuestions(tagID uint, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return a.db.Where("tag_id = ? AND question_id IN ?", tagID, ids).Delete(&QuestionTag{}).Error
+}
+
+func (a *App) GetQuestionTags(id uint) []Tag {
+	tags := []Tag{}
+	a.db.Where("id IN (?)", a.db.Model(&QuestionTag{}).Select("tag_id").Where("question_id = ?", id)).
+		Order("name ASC").Find(&tags)
+	return tags
+}
+
+// SetTagFilter limits the grid, stats and practice sessions to one tag.
+// Zero shows the whole bank again.
+func (a *App) SetTagFilter(tagID uint) error {
+	if tagID != 0 {
+		var tag Tag
+		if err := a.db.First(&tag, tagID).Error; err != nil || tag.BankID != a.activeBank {
+			return errors.New("标签不存在")
+		}
+	}
+	a.activeTag = tagID
+	a.mistakeSession = make(map[uint]int)
+	a.reviewSession = make(map[uint]int)
+	if a.ReviewMode {
+		a.SetReviewMode(true, 0)
+	}
+	return nil
+}
+
+func (a *App) GetTagFilter() uint {
+	return a.activeTag
+}
+
+type TagStats struct {
+	TagID   uint   `json:"tag_id"`
+	Name    string `json:"name"`
+	Total   int64  `json:"total"`
+	Done    int64  `json:"done"`
+	Correct int64  `json:"correct"`
+	Wrong   int64  `json:"wrong"`
+}
+
+// GetTagStats reports progress per tag in the active bank.
+func (a *App) GetTagStats() []TagStats {
+	stats := []TagStats{}
+	a.db.Table("tags").
+		Select(`tags.id AS tag_id, tags.name,
+			COUNT(questions.id) AS total,
+			SUM(CASE WHEN user_progresses.status > 0 THEN 1 ELSE 0 END) AS done,
+			SUM(CASE WHEN user_progresses.status = 1 THEN 1 ELSE 0 END) AS correct,
+			SUM(CASE WHEN user_progresses.status = 2 THEN 1 ELSE 0 END) AS wrong`).
+		Joins("JOIN question_tags ON question_tags.tag_id = tags.id").
+		Joins("JOIN questions ON questions.id = question_tags.question_id AND questions.retired = ?", false).
+		Joins("LEFT JOIN user_progresses ON user_progresses.question_id = questions.id AND user_progresses.profile_id = ?", a.activeProfile).
+		Where("tags.bank_id = ?", a.activeBank).
+		Group("tags.id").
+		Order("tags.name ASC").
+		Scan(&stats)
+	return stats
+}
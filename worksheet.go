@@ -0,0 +1,384 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+
+	"quiz-app/internal/quizbank"
+)
+
+type WorksheetOptions struct {
+	Filter         string   `json:"filter"` // all, mistakes, marked, tag
+	TagID          uint     `json:"tag_id"` // For the tag filter
+	Types          []string `json:"types"`  // Empty for all types
+	Limit          int      `json:"limit"`  // Random sample of this many questions, 0 for all
+	Title          string   `json:"title"`
+	ShuffleOptions bool     `json:"shuffle_options"`
+	AIExplanations bool     `json:"ai_explanations"` // Add AI explanations to the appendix
+	FontPath       string   `json:"font_path"`       // TTF with Chinese glyphs for PDF, searched if empty
+}
+
+type worksheetItem struct {
+	No            int
+	Number        uint
+	Type          string
+	Content       string
+	Options       []string
+	Answer        string
+	Explanation   string
+	AIExplanation string
+}
+
+type worksheetSection struct {
+	Type  string
+	Items []worksheetItem
+}
+
+type worksheet struct {
+	Title    string
+	Created  string
+	Count    int
+	Sections []worksheetSection
+	WithAI   bool
+}
+
+// Options in the bank look like "A、 text"
+var optionLabel = regexp.MustCompile(`^\s*([A-H])\s*[、.．:：]\s*`)
+
+// shuffleOptions relabels the options in random order and maps the key to
+// the new letters. Questions whose options are not labelled stay as they are.
+func shuffleOptions(q Question, options []string, rng *rand.Rand) ([]string, string) {
+	key := quizbank.NormalizeAnswer(q.Type, q.Answer)
+	if q.Type == "判断题" || len(options) < 2 {
+		return options, key
+	}
+	texts := make([]string, len(options))
+	for i, opt := range options {
+		m := optionLabel.FindStringSubmatch(opt)
+		if m == nil || m[1] != string(rune('A'+i)) {
+			return options, key
+		}
+		texts[i] = opt[len(m[0]):]
+	}
+
+	perm := rng.Perm(len(options))
+	shuffled := make([]string, len(options))
+	newLetter := make(map[string]string, len(options))
+	for i, from := range perm {
+		label := string(rune('A' + i))
+		shuffled[i] = label + "、 " + texts[from]
+		newLetter[string(rune('A'+from))] = label
+	}
+
+	var letters []string
+	for _, l := range quizbank.AnswerLetters(q.Type, q.Answer) {
+		// A key letter with no option would be lost, keep the original order
+		if newLetter[l] == "" {
+			return options, key
+		}
+		letters = append(letters, newLetter[l])
+	}
+	sort.Strings(letters)
+	return shuffled, strings.Join(letters, ",")
+}
+
+// buildWorksheet selects the questions and lays them out by type.
+func (a *App) buildWorksheet(opts WorksheetOptions) (worksheet, error) {
+	ids, err := a.questionIDsBySelection(opts.Filter, opts.TagID)
+	if err != nil {
+		return worksheet{}, err
+	}
+
+	query := a.db.Where("id IN ?", ids)
+	if len(opts.Types) > 0 {
+		query = query.Where("type IN ?", opts.Types)
+	}
+	var questions []Question
+	if len(ids) > 0 {
+		query.Order("number ASC").Find(&questions)
+	}
+	if len(questions) == 0 {
+		return worksheet{}, errors.New("所选范围内没有题目")
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	if opts.Limit > 0 && opts.Limit < len(questions) {
+		rng.Shuffle(len(questions), func(i, j int) { questions[i], questions[j] = questions[j], questions[i] })
+		questions = questions[:opts.Limit]
+		sort.Slice(questions, func(i, j int) bool { return questions[i].Number < questions[j].Number })
+	}
+
+	title := strings.TrimSpace(opts.Title)
+	if title == "" {
+		var bank Bank
+		a.db.First(&bank, a.activeBank)
+		title = bank.Name + " 练习卷"
+	}
+	sheet := worksheet{
+		Title:   title,
+		Created: time.Now().Format("2006-01-02"),
+		Count:   len(questions),
+		WithAI:  opts.AIExplanations,
+	}
+
+	// Exam order first, then any other types in the bank
+	order := append([]string{}, examTypes...)
+	byType := make(map[string][]Question)
+	for _, q := range questions {
+		if _, ok := byType[q.Type]; !ok && !contains(order, q.Type) {
+			order = append(order, q.Type)
+		}
+		byType[q.Type] = append(byType[q.Type], q)
+	}
+
+	no := 0
+	for _, t := range order {
+		if len(byType[t]) == 0 {
+			continue
+		}
+		section := worksheetSection{Type: t}
+		for _, q := range byType[t] {
+			no++
+			var options []string
+			json.Unmarshal([]byte(q.Options), &options)
+			answer := quizbank.NormalizeAnswer(q.Type, q.Answer)
+			if opts.ShuffleOptions {
+				options, answer = shuffleOptions(q, options, rng)
+			}
+			item := worksheetItem{
+				No:          no,
+				Number:      q.Number,
+				Type:        q.Type,
+				Content:     q.Content,
+				Options:     options,
+				Answer:      answer,
+				Explanation: q.Explanation,
+			}
+			if opts.AIExplanations {
+				item.AIExplanation = q.AIExplanation
+			}
+			section.Items = append(section.Items, item)
+		}
+		sheet.Sections = append(sheet.Sections, section)
+	}
+	return sheet, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var worksheetTemplate = template.Must(template.New("worksheet").Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: "PingFang SC", "Microsoft YaHei", "Noto Sans CJK SC", sans-serif; max-width: 800px; margin: 24px auto; line-height: 1.6; color: #222; }
+h1 { text-align: center; margin-bottom: 4px; }
+.meta { text-align: center; color: #666; margin-bottom: 24px; }
+h2 { border-bottom: 1px solid #999; padding-bottom: 4px; }
+.question { margin-bottom: 16px; page-break-inside: avoid; }
+.options { list-style: none; padding-left: 2em; margin: 4px 0; }
+.appendix { page-break-before: always; }
+.key { margin-bottom: 10px; page-break-inside: avoid; }
+.key .explanation, .key .ai { color: #444; white-space: pre-wrap; margin-left: 2em; }
+.key .ai { color: #666; }
+@media print { body { margin: 0 auto; } }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="meta">共 {{.Count}} 题 · {{.Created}} · 姓名＿＿＿＿＿＿ 得分＿＿＿＿</div>
+{{range .Sections}}
+<h2>{{.Type}}</h2>
+{{range .Items}}
+<div class="question">
+<div>{{.No}}. {{.Content}}</div>
+<ul class="options">{{range .Options}}<li>{{.}}</li>{{end}}</ul>
+</div>
+{{end}}
+{{end}}
+<div class="appendix">
+<h1>答案与解析</h1>
+{{$ai := .WithAI}}
+{{range .Sections}}
+<h2>{{.Type}}</h2>
+{{range .Items}}
+<div class="key">
+<div><strong>{{.No}}.</strong> 答案：{{.Answer}}（题库第 {{.Number}} 题）</div>
+{{if .Explanation}}<div class="explanation">{{.Explanation}}</div>{{end}}
+{{if and $ai .AIExplanation}}<div class="ai">{{.AIExplanation}}</div>{{end}}
+</div>
+{{end}}
+{{end}}
+</div>
+</body>
+</html>
+`))
+
+// ExportWorksheetHTML writes a self-contained printable paper with an answer
+// appendix and returns the number of questions.
+func (a *App) ExportWorksheetHTML(path string, opts WorksheetOptions) (int, error) {
+	sheet, err := a.buildWorksheet(opts)
+	if err != nil {
+		return 0, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, fmt.Errorf("写入文件失败: %w", err)
+	}
+	if err := worksheetTemplate.Execute(f, sheet); err != nil {
+		f.Close()
+		return 0, fmt.Errorf("生成试卷失败: %w", err)
+	}
+	return sheet.Count, f.Close()
+}
+
+// Fonts with Chinese glyphs that fpdf can embed (TTF, not TTC)
+var cjkFontPaths = []string{
+	`C:\Windows\Fonts\simhei.ttf`,
+	`C:\Windows\Fonts\simkai.ttf`,
+	`C:\Windows\Fonts\simfang.ttf`,
+	"/System/Library/Fonts/Supplemental/Arial Unicode.ttf",
+	"/Library/Fonts/Arial Unicode.ttf",
+	"/usr/share/fonts/truetype/droid/DroidSansFallbackFull.ttf",
+	"/usr/share/fonts/truetype/arphic-gbsn00lp/gbsn00lp.ttf",
+	"/usr/share/fonts/wenquanyi/wqy-zenhei/wqy-zenhei.ttf",
+}
+
+func findCJKFont(path string) (string, error) {
+	if path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return "", fmt.Errorf("字体文件不存在: %s", path)
+		}
+		return path, nil
+	}
+	for _, p := range cjkFontPaths {
+		if _, err := os.Stat(p); err == nil {
+			return p, nil
+		}
+	}
+	return "", errors.New("未找到中文字体，请指定一个 .ttf 字体文件")
+}
+
+// ExportWorksheetPDF renders the same paper as ExportWorksheetHTML to PDF.
+func (a *App) ExportWorksheetPDF(path string, opts WorksheetOptions) (int, error) {
+	font, err := findCJKFont(opts.FontPath)
+	if err != nil {
+		return 0, err
+	}
+	sheet, err := a.buildWorksheet(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	pdf := fpdf.New("P", "mm", "A4", filepath.Dir(font))
+	pdf.SetMargins(18, 18, 18)
+	pdf.SetAutoPageBreak(true, 18)
+	pdf.AddUTF8Font("cjk", "", filepath.Base(font))
+	if pdf.Err() {
+		return 0, fmt.Errorf("无法加载字体 %s: %w", font, pdf.Error())
+	}
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("cjk", "", 9)
+		pdf.CellFormat(0, 6, fmt.Sprintf("%d", pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+
+	heading := func(text string, size float64) {
+		pdf.SetFont("cjk", "", size)
+		pdf.MultiCell(0, size*0.6, text, "", "C", false)
+		pdf.Ln(2)
+	}
+	section := func(text string) {
+		pdf.Ln(2)
+		pdf.SetFont("cjk", "", 13)
+		pdf.MultiCell(0, 8, text, "B", "L", false)
+		pdf.Ln(2)
+		pdf.SetFont("cjk", "", 11)
+	}
+
+	pdf.AddPage()
+	heading(sheet.Title, 18)
+	heading(fmt.Sprintf("共 %d 题 · %s · 姓名＿＿＿＿＿＿ 得分＿＿＿＿", sheet.Count, sheet.Created), 10)
+	for _, s := range sheet.Sections {
+		section(s.Type)
+		for _, item := range s.Items {
+			pdf.MultiCell(0, 6, fmt.Sprintf("%d. %s", item.No, item.Content), "", "L", false)
+			for _, opt := range item.Options {
+				pdf.SetX(24)
+				pdf.MultiCell(0, 6, opt, "", "L", false)
+			}
+			pdf.Ln(3)
+		}
+	}
+
+	pdf.AddPage()
+	heading("答案与解析", 16)
+	for _, s := range sheet.Sections {
+		section(s.Type)
+		for _, item := range s.Items {
+			pdf.MultiCell(0, 6, fmt.Sprintf("%d. 答案：%s（题库第 %d 题）", item.No, item.Answer, item.Number), "", "L", false)
+			if item.Explanation != "" {
+				pdf.SetX(24)
+				pdf.MultiCell(0, 6, item.Explanation, "", "L", false)
+			}
+			if sheet.WithAI && item.AIExplanation != "" {
+				pdf.SetX(24)
+				pdf.SetTextColor(100, 100, 100)
+				pdf.MultiCell(0, 6, item.AIExplanation, "", "L", false)
+				pdf.SetTextColor(0, 0, 0)
+			}
+			pdf.Ln(2)
+		}
+	}
+
+	if err := pdf.OutputFileAndClose(path); err != nil {
+		return 0, fmt.Errorf("生成 PDF 失败: %w", err)
+	}
+	return sheet.Count, nil
+}
+
+// ExportWorksheetDialog asks where to save the paper; the format follows the
+// chosen extension (.pdf or .html). An empty path means the dialog was cancelled.
+func (a *App) ExportWorksheetDialog(opts WorksheetOptions) (string, error) {
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "导出练习卷",
+		DefaultFilename: "worksheet.html",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "网页 (*.html)", Pattern: "*.html"},
+			{DisplayName: "PDF (*.pdf)", Pattern: "*.pdf"},
+		},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".pdf":
+		_, err = a.ExportWorksheetPDF(path, opts)
+	case ".html", ".htm":
+		_, err = a.ExportWorksheetHTML(path, opts)
+	default:
+		path += ".html"
+		_, err = a.ExportWorksheetHTML(path, opts)
+	}
+	return path, err
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestShuffleOptions(t *testing.T) {
+	options := []string{"A、甲", "B、乙", "C、丙", "D、丁"}
+	q := Question{Type: "多选题", Answer: "A,C"}
+	shuffled, key := shuffleOptions(q, options, rand.New(rand.NewSource(1)))
+	for _, l := range strings.Split(key, ",") {
+		text := shuffled[l[0]-'A']
+		if !strings.HasSuffix(text, "甲") && !strings.HasSuffix(text, "丙") {
+			t.Errorf("key %s points at %q", l, text)
+		}
+	}
+
+	// A key letter without an option keeps the original order and key
+	q.Answer = "B,E"
+	shuffled, key = shuffleOptions(q, options, rand.New(rand.NewSource(1)))
+	if !reflect.DeepEqual(shuffled, options) || key != "B,E" {
+		t.Errorf("got %q, %q; want the original options and B,E", shuffled, key)
+	}
+}